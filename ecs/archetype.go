@@ -0,0 +1,321 @@
+package ecs
+
+import "reflect"
+
+// ArchetypeStore is a component storage backend which packs the components of all entities with the same set of
+// component types (an archetype) into contiguous slices, one per component type. Iterating over these with
+// ArchetypeEach and friends avoids both pointer chasing and per-entity reflection, which matters for systems that deal
+// with many thousands of entities. Within an archetype, entities are visited in the order they were added.
+//
+// Components given as structs are stored by value, so unlike the rest of the ECS, the pointers handed out for them are
+// only valid until the next call to Add or Remove. Components given as pointers are stored as pointers, sharing them
+// with whoever else holds them - this is how the ECS itself groups every entity's components by archetype, so that
+// Query and Each only visit matching entities. An ArchetypeStore is not safe for concurrent use; it is generally owned
+// by a single system.
+type ArchetypeStore struct {
+	archetypes map[uint64][]*archetype // Archetypes by the hash of their component types, see typesHash.
+	order      []*archetype            // All archetypes, in order of creation, so that iteration is deterministic.
+	locations  map[EntityID]archetypeLocation
+	typeHashes map[reflect.Type]uint64 // The hash of each component type seen so far.
+	last       *archetype              // The archetype most recently added to, which the next entity often shares.
+}
+
+// archetype stores the components of all entities with one particular set of component types.
+// Removed entities leave an empty row behind, with an ID of zero, so that the others keep their order without being
+// moved. The empty rows are dropped once they make up half of the archetype.
+type archetype struct {
+	columns map[reflect.Type]reflect.Value // Each column is an addressable []T, where T is a component type.
+	types   []reflect.Type                 // The component types, in the order the archetype was created with.
+	ordered []reflect.Value                // The column of each type in types.
+	ids     []EntityID
+	removed int // How many of the rows are empty.
+}
+
+// archetypeLocation identifies where an entity's components are stored.
+type archetypeLocation struct {
+	archetype *archetype
+	row       int
+}
+
+// NewArchetypeStore initializes and returns an empty ArchetypeStore.
+func NewArchetypeStore() *ArchetypeStore {
+	return &ArchetypeStore{
+		archetypes: make(map[uint64][]*archetype),
+		locations:  make(map[EntityID]archetypeLocation),
+		typeHashes: make(map[reflect.Type]uint64),
+	}
+}
+
+// Add stores an entity with the given components, which may be structs, copied into the store, or pointers to structs,
+// which are stored as they are. If the entity is already stored, its components are replaced. If several components
+// have the same type, the last one is stored.
+func (s *ArchetypeStore) Add(id EntityID, components ...interface{}) {
+	var typesBuffer [8]reflect.Type
+	types := typesBuffer[:0]
+
+	for _, component := range components {
+		t := reflect.TypeOf(component)
+		if earlier := indexOfType(types, t); earlier >= 0 {
+			// Drop the earlier component and start again. This is rare, so the extra work doesn't matter.
+			s.Add(id, append(append([]interface{}(nil), components[:earlier]...), components[earlier+1:]...)...)
+			return
+		}
+		types = append(types, t)
+	}
+
+	s.set(id, types, components)
+}
+
+// set stores an entity with components of the given types, replacing any it already had.
+func (s *ArchetypeStore) set(id EntityID, types []reflect.Type, components []interface{}) {
+	s.Remove(id)
+
+	// Entities are often added in runs of the same kind, with their components in the same order, in which case the
+	// archetype and its columns don't need to be looked up.
+	arch := s.last
+	if arch == nil || !sameTypes(arch.types, types) {
+		arch = s.archetypeFor(types)
+		s.last = arch
+	}
+
+	if sameTypes(arch.types, types) {
+		for i, column := range arch.ordered {
+			appendValue(column, reflect.ValueOf(components[i]))
+		}
+	} else {
+		for i, t := range types {
+			appendValue(arch.columns[t], reflect.ValueOf(components[i]))
+		}
+	}
+
+	s.locations[id] = archetypeLocation{arch, len(arch.ids)}
+	arch.ids = append(arch.ids, id)
+}
+
+// appendValue appends a value to an addressable slice, growing it in place.
+func appendValue(column reflect.Value, value reflect.Value) {
+	length := column.Len()
+	if length == column.Cap() {
+		grown := reflect.MakeSlice(column.Type(), length, 2*length+16)
+		reflect.Copy(grown, column)
+		column.Set(grown)
+	}
+
+	column.SetLen(length + 1)
+	column.Index(length).Set(value)
+}
+
+// Remove removes an entity and its components from the store, if it is stored.
+func (s *ArchetypeStore) Remove(id EntityID) {
+	location, ok := s.locations[id]
+	if !ok {
+		return
+	}
+	delete(s.locations, id)
+
+	// Clear the row, so that the store doesn't keep the components alive.
+	arch := location.archetype
+	for t, column := range arch.columns {
+		column.Index(location.row).Set(reflect.Zero(t))
+	}
+	arch.ids[location.row] = 0
+	arch.removed++
+
+	if arch.removed > len(arch.ids)/2 {
+		s.compact(arch)
+	}
+}
+
+// compact drops the empty rows of an archetype, moving the rest up without changing their order.
+func (s *ArchetypeStore) compact(arch *archetype) {
+	kept := 0
+	for row, id := range arch.ids {
+		if id == 0 {
+			continue
+		}
+
+		if row != kept {
+			for _, column := range arch.columns {
+				column.Index(kept).Set(column.Index(row))
+			}
+			arch.ids[kept] = id
+			s.locations[id] = archetypeLocation{arch, kept}
+		}
+		kept++
+	}
+
+	for t, column := range arch.columns {
+		for row := kept; row < column.Len(); row++ {
+			column.Index(row).Set(reflect.Zero(t))
+		}
+		column.SetLen(kept)
+	}
+	arch.ids = arch.ids[:kept]
+	arch.removed = 0
+}
+
+// Get returns the component of the given type on an entity, if it has one. Components stored by value are returned as
+// pointers into the store, so componentType should be the struct type, while components stored as pointers are
+// returned as they are.
+func (s *ArchetypeStore) Get(id EntityID, componentType reflect.Type) (interface{}, bool) {
+	location, ok := s.locations[id]
+	if !ok {
+		return nil, false
+	}
+
+	column, ok := location.archetype.columns[componentType]
+	if !ok {
+		return nil, false
+	}
+
+	if componentType.Kind() == reflect.Ptr {
+		return column.Index(location.row).Interface(), true
+	}
+	return column.Index(location.row).Addr().Interface(), true
+}
+
+// Len returns the number of entities in the store.
+func (s *ArchetypeStore) Len() int {
+	return len(s.locations)
+}
+
+// archetypeFor finds or creates the archetype for the given set of component types.
+func (s *ArchetypeStore) archetypeFor(types []reflect.Type) *archetype {
+	hash := s.typesHash(types)
+	for _, arch := range s.archetypes[hash] {
+		if len(arch.columns) == len(types) && arch.has(types...) {
+			return arch
+		}
+	}
+
+	arch := &archetype{columns: make(map[reflect.Type]reflect.Value, len(types)), types: append([]reflect.Type(nil), types...)}
+	for _, t := range types {
+		column := reflect.New(reflect.SliceOf(t)).Elem()
+		arch.columns[t] = column
+		arch.ordered = append(arch.ordered, column)
+	}
+
+	s.archetypes[hash] = append(s.archetypes[hash], arch)
+	s.order = append(s.order, arch)
+	return arch
+}
+
+// typesHash returns a hash of a set of component types, which doesn't depend on their order. Each type is given a
+// hash of its own the first time it is seen, and these are summed.
+func (s *ArchetypeStore) typesHash(types []reflect.Type) uint64 {
+	var hash uint64
+	for _, t := range types {
+		typeHash, ok := s.typeHashes[t]
+		if !ok {
+			typeHash = mixHash(uint64(len(s.typeHashes) + 1))
+			s.typeHashes[t] = typeHash
+		}
+		hash += typeHash
+	}
+	return hash
+}
+
+// mixHash spreads the bits of a small number across a 64-bit hash (the splitmix64 finalizer).
+func mixHash(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// sameTypes returns true if two lists hold the same types in the same order.
+func sameTypes(a []reflect.Type, b []reflect.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indexOfType returns the index of a type within a list, or -1 if it isn't there.
+func indexOfType(types []reflect.Type, t reflect.Type) int {
+	for i, other := range types {
+		if other == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// has returns true if the archetype has columns for all of the given component types.
+func (a *archetype) has(types ...reflect.Type) bool {
+	for _, t := range types {
+		if _, ok := a.columns[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// archetypesWith returns all archetypes with entities which have all of the given component types.
+func (s *ArchetypeStore) archetypesWith(types ...reflect.Type) []*archetype {
+	var result []*archetype
+
+	for _, arch := range s.order {
+		if len(arch.ids) > arch.removed && arch.has(types...) {
+			result = append(result, arch)
+		}
+	}
+
+	return result
+}
+
+// ArchetypeEach calls fn for every entity in the store with a component of type A, stored by value.
+func ArchetypeEach[A any](s *ArchetypeStore, fn func(id EntityID, a *A)) {
+	typeA := reflect.TypeOf((*A)(nil)).Elem()
+
+	for _, arch := range s.archetypesWith(typeA) {
+		as := arch.columns[typeA].Interface().([]A)
+
+		for i, id := range arch.ids {
+			if id == 0 {
+				continue
+			}
+			fn(id, &as[i])
+		}
+	}
+}
+
+// ArchetypeEach2 calls fn for every entity in the store with components of types A and B, stored by value.
+func ArchetypeEach2[A, B any](s *ArchetypeStore, fn func(id EntityID, a *A, b *B)) {
+	typeA, typeB := reflect.TypeOf((*A)(nil)).Elem(), reflect.TypeOf((*B)(nil)).Elem()
+
+	for _, arch := range s.archetypesWith(typeA, typeB) {
+		as := arch.columns[typeA].Interface().([]A)
+		bs := arch.columns[typeB].Interface().([]B)
+
+		for i, id := range arch.ids {
+			if id == 0 {
+				continue
+			}
+			fn(id, &as[i], &bs[i])
+		}
+	}
+}
+
+// ArchetypeEach3 calls fn for every entity in the store with components of types A, B and C, stored by value.
+func ArchetypeEach3[A, B, C any](s *ArchetypeStore, fn func(id EntityID, a *A, b *B, c *C)) {
+	typeA, typeB := reflect.TypeOf((*A)(nil)).Elem(), reflect.TypeOf((*B)(nil)).Elem()
+	typeC := reflect.TypeOf((*C)(nil)).Elem()
+
+	for _, arch := range s.archetypesWith(typeA, typeB, typeC) {
+		as := arch.columns[typeA].Interface().([]A)
+		bs := arch.columns[typeB].Interface().([]B)
+		cs := arch.columns[typeC].Interface().([]C)
+
+		for i, id := range arch.ids {
+			if id == 0 {
+				continue
+			}
+			fn(id, &as[i], &bs[i], &cs[i])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Position struct{ X, Y float64 }
+type Velocity struct{ X, Y float64 }
+type Tag struct{}
+
+func TestArchetypeStoreAddRemove(t *testing.T) {
+	s := NewArchetypeStore()
+	for i := 1; i <= 3; i++ {
+		s.Add(EntityID(i), Position{float64(i), 0}, &Velocity{1, 0})
+	}
+	s.Add(4, Position{4, 0})
+
+	s.Remove(2)
+	if s.Len() != 3 {
+		t.Fatalf("got %d entities, want 3", s.Len())
+	}
+	if _, ok := s.Get(2, reflect.TypeOf(Position{})); ok {
+		t.Error("removed entity still has a position")
+	}
+
+	// The other entities keep their components.
+	for _, id := range []EntityID{1, 3, 4} {
+		p, ok := s.Get(id, reflect.TypeOf(Position{}))
+		if !ok || p.(*Position).X != float64(id) {
+			t.Errorf("entity %v has position %v, %v, want x = %v", id, p, ok, id)
+		}
+	}
+
+	var visited []EntityID
+	ArchetypeEach[Position](s, func(id EntityID, p *Position) { visited = append(visited, id) })
+	if len(visited) != 3 {
+		t.Errorf("visited %v, want 3 entities", visited)
+	}
+}
+
+func TestArchetypeStoreKeepsOrder(t *testing.T) {
+	s := NewArchetypeStore()
+	for i := 1; i <= 10; i++ {
+		s.Add(EntityID(i), Position{float64(i), 0}, Velocity{})
+	}
+
+	// Removing most of the entities drops their rows, moving the rest up.
+	for _, id := range []EntityID{2, 3, 5, 7, 8, 9} {
+		s.Remove(id)
+	}
+	s.Add(11, Velocity{}, Position{11, 0})
+	s.Add(12, Position{12, 0})
+
+	var visited []EntityID
+	ArchetypeEach2[Position, Velocity](s, func(id EntityID, p *Position, v *Velocity) {
+		if p.X != float64(id) {
+			t.Errorf("entity %v has position %v", id, p.X)
+		}
+		visited = append(visited, id)
+	})
+	if want := []EntityID{1, 4, 6, 10, 11}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+
+	for _, id := range []EntityID{1, 4, 6, 10, 11, 12} {
+		if p, ok := s.Get(id, reflect.TypeOf(Position{})); !ok || p.(*Position).X != float64(id) {
+			t.Errorf("entity %v has position %v, %v", id, p, ok)
+		}
+	}
+}
+
+func TestArchetypeStoreDuplicateTypes(t *testing.T) {
+	s := NewArchetypeStore()
+	s.Add(1, Position{1, 0}, Velocity{}, Position{2, 0})
+
+	if p, ok := s.Get(1, reflect.TypeOf(Position{})); !ok || p.(*Position).X != 2 {
+		t.Errorf("got position %v, %v, want the last one given", p, ok)
+	}
+	if _, ok := s.Get(1, reflect.TypeOf(Velocity{})); !ok {
+		t.Error("the velocity was lost")
+	}
+}
+
+func TestArchetypeStorePointersAreShared(t *testing.T) {
+	s := NewArchetypeStore()
+	v := &Velocity{1, 0}
+	s.Add(1, v)
+	v.X = 5
+
+	got, ok := s.Get(1, reflect.TypeOf(v))
+	if !ok || got != v {
+		t.Errorf("got %v, %v, want the same pointer that was added", got, ok)
+	}
+}
+
+func TestQueryFollowsComponentChanges(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+
+	p, v := &Position{}, &Velocity{}
+	moving := e.AddEntity(&Position{}, &Velocity{})
+	still := e.AddEntity(p)
+	e.Step(1, 0)
+
+	e.AddComponent(still, v)
+	e.Step(1, 0)
+
+	var visited []EntityID
+	Each2(&e, func(id EntityID, _ *Position, b *Velocity) { visited = append(visited, id) })
+	if len(visited) != 2 || visited[0] != moving || visited[1] != still {
+		t.Fatalf("visited %v, want %v then %v", visited, moving, still)
+	}
+	if got, _ := ComponentOf[Velocity](&e, still); got != v {
+		t.Error("the added component wasn't shared")
+	}
+
+	e.RemoveComponent(moving, reflect.TypeOf(&Velocity{}))
+	e.RemoveEntity(still)
+	e.Step(1, 0)
+
+	if ids := e.Query(reflect.TypeOf(&Position{}), reflect.TypeOf(&Velocity{})); len(ids) != 0 {
+		t.Errorf("got %v, want no moving entities", ids)
+	}
+	if ids := e.Query(reflect.TypeOf(&Position{})); len(ids) != 1 || ids[0] != moving {
+		t.Errorf("got %v, want %v", ids, moving)
+	}
+}
+
+// benchmarkEntities is how many entities the benchmarks are run with. Half of them are tagged, so that they are split
+// across two archetypes.
+const benchmarkEntities = 10000
+
+// addedEvents returns the events adding the benchmarks' entities.
+func addedEvents() []EntityAddedEvent {
+	events := make([]EntityAddedEvent, benchmarkEntities)
+	for i := range events {
+		components := map[reflect.Type]interface{}{
+			reflect.TypeOf(&Position{}): &Position{},
+			reflect.TypeOf(&Velocity{}): &Velocity{1, 1},
+		}
+		if i%2 == 0 {
+			components[reflect.TypeOf(&Tag{})] = &Tag{}
+		}
+		events[i] = EntityAddedEvent{EntityID(i + 1), components}
+	}
+	return events
+}
+
+type eMover struct {
+	*Position
+	*Velocity
+}
+
+func BenchmarkIterate(b *testing.B) {
+	events := addedEvents()
+
+	b.Run("UnpackEntity", func(b *testing.B) {
+		movers := make(map[EntityID]eMover)
+		for _, event := range events {
+			UnpackEntity(event, &movers)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, mover := range movers {
+				mover.Position.X += mover.Velocity.X
+			}
+		}
+	})
+
+	b.Run("Each2", func(b *testing.B) {
+		e := NewECS()
+		for _, event := range events {
+			e.prepareEvent(event)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			Each2(&e, func(id EntityID, p *Position, v *Velocity) {
+				p.X += v.X
+			})
+		}
+	})
+
+	b.Run("ArchetypeEach2", func(b *testing.B) {
+		s := NewArchetypeStore()
+		for _, event := range events {
+			if _, tagged := event.Components[reflect.TypeOf(&Tag{})]; tagged {
+				s.Add(event.ID, Position{}, Velocity{1, 1}, Tag{})
+			} else {
+				s.Add(event.ID, Position{}, Velocity{1, 1})
+			}
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ArchetypeEach2(s, func(id EntityID, p *Position, v *Velocity) {
+				p.X += v.X
+			})
+		}
+	})
+}
+
+func BenchmarkAdd(b *testing.B) {
+	events := addedEvents()
+
+	b.Run("UnpackEntity", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			movers := make(map[EntityID]eMover)
+			for _, event := range events {
+				UnpackEntity(event, &movers)
+			}
+		}
+	})
+
+	b.Run("ArchetypeStore", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s := NewArchetypeStore()
+			for _, event := range events {
+				s.Add(event.ID, Position{}, Velocity{1, 1})
+			}
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package ecs
+
+import "time"
+
+// Clock provides the ECS with the amount of time that passes between frames.
+type Clock interface {
+	// Tick returns the number of seconds elapsed since the previous call to Tick.
+	Tick() float64
+}
+
+// RealClock is a Clock which measures wall-clock time.
+type RealClock struct {
+	LastFrame time.Time
+}
+
+// NewRealClock returns a RealClock which begins measuring from the current time.
+func NewRealClock() *RealClock {
+	return &RealClock{time.Now()}
+}
+
+// Tick returns the wall-clock time elapsed since the last tick.
+func (c *RealClock) Tick() float64 {
+	thisFrame := time.Now()
+	delta := thisFrame.Sub(c.LastFrame).Seconds()
+	c.LastFrame = thisFrame
+	return delta
+}
+
+// FixedClock is a Clock which always reports the same delta, regardless of how much time has actually passed.
+// This is useful for running the simulation deterministically, e.g. in tests or without a window.
+type FixedClock struct {
+	Delta float64
+}
+
+// Tick returns the fixed delta.
+func (c *FixedClock) Tick() float64 {
+	return c.Delta
+}
@@ -7,22 +7,85 @@ type UpdateBeginEvent struct {
 	Delta float64
 }
 
+// LateUpdateEvent is triggered once all of an update's other events have been handled, just before UpdateEndEvent.
+// This is used for work which needs everything to have moved first, such as collision detection. Events published to
+// ev.Next while handling it are still part of the same update.
+type LateUpdateEvent struct {
+	Delta float64
+}
+
 // UpdateEndEvent is triggered after UpdateBeginEvent.
 // This is used to ensure certain tasks are completed before moving on to the next frame.
 type UpdateEndEvent struct {
 	Delta float64
 }
 
+// RenderEvent is triggered once per rendered frame, after any updates for that frame.
+// When the ECS uses a fixed step, Alpha is the fraction of a step that has elapsed since the last update, which can be
+// used to interpolate between the previous and current state. Otherwise, Alpha is always 1.
+type RenderEvent struct {
+	Alpha float64
+}
+
 // SetupEvent is triggered once the ECS is ready to run - it is safe to add required entities here.
 type SetupEvent struct{}
 
 // EntityAddedEvent is triggered when an entity is added. Stores the entity ID and a map of all components.
 type EntityAddedEvent struct {
-	ID         uint64
+	ID         EntityID
 	Components map[reflect.Type]interface{}
 }
 
 // EntityRemovedEvent is triggered when an entity is removed.
 type EntityRemovedEvent struct {
-	ID uint64
+	ID EntityID
+}
+
+// ComponentAddedEvent is triggered when a component is added to an existing entity.
+// Components stores the entity's full set of components after the addition.
+type ComponentAddedEvent struct {
+	ID         EntityID
+	Component  interface{}
+	Components map[reflect.Type]interface{}
+}
+
+// ComponentRemovedEvent is triggered when a component is removed from an existing entity.
+// Components stores the entity's full set of components after the removal.
+type ComponentRemovedEvent struct {
+	ID         EntityID
+	Type       reflect.Type
+	Component  interface{}
+	Components map[reflect.Type]interface{}
+}
+
+// TargetEntity returns the entity gaining a component.
+func (e ComponentAddedEvent) TargetEntity() EntityID {
+	return e.ID
+}
+
+// TargetEntity returns the entity losing a component.
+func (e ComponentRemovedEvent) TargetEntity() EntityID {
+	return e.ID
+}
+
+// EntityChange is implemented by all events which change the set of components an entity has: EntityAddedEvent,
+// ComponentAddedEvent and ComponentRemovedEvent. Systems can handle all of these at once by passing them to
+// RefreshEntity.
+type EntityChange interface {
+	ChangedEntity() (EntityID, map[reflect.Type]interface{})
+}
+
+// ChangedEntity returns the ID and components of the added entity.
+func (e EntityAddedEvent) ChangedEntity() (EntityID, map[reflect.Type]interface{}) {
+	return e.ID, e.Components
+}
+
+// ChangedEntity returns the ID and new components of the entity.
+func (e ComponentAddedEvent) ChangedEntity() (EntityID, map[reflect.Type]interface{}) {
+	return e.ID, e.Components
+}
+
+// ChangedEntity returns the ID and remaining components of the entity.
+func (e ComponentRemovedEvent) ChangedEntity() (EntityID, map[reflect.Type]interface{}) {
+	return e.ID, e.Components
 }
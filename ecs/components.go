@@ -0,0 +1,254 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// componentStore keeps track of the components currently attached to every entity.
+// Entities enter the store when their EntityAddedEvent is published, and leave it once their EntityRemovedEvent has
+// been handled by all systems. Entities are also grouped by archetype, so that queries only visit matching entities.
+type componentStore struct {
+	mutex      sync.RWMutex
+	entities   map[EntityID]map[reflect.Type]interface{}
+	archetypes *ArchetypeStore
+}
+
+// newComponentStore initializes and returns an empty componentStore.
+func newComponentStore() *componentStore {
+	return &componentStore{
+		entities:   make(map[EntityID]map[reflect.Type]interface{}),
+		archetypes: NewArchetypeStore(),
+	}
+}
+
+// set replaces the components of an entity. The mutex must be held.
+func (s *componentStore) set(id EntityID, components map[reflect.Type]interface{}) {
+	s.entities[id] = components
+
+	var typesBuffer [8]reflect.Type
+	var valuesBuffer [8]interface{}
+	types, values := typesBuffer[:0], valuesBuffer[:0]
+	for t, component := range components {
+		types = append(types, t)
+		values = append(values, component)
+	}
+	s.archetypes.set(id, types, values)
+}
+
+// remove removes an entity and its components. The mutex must be held.
+func (s *componentStore) remove(id EntityID) {
+	delete(s.entities, id)
+	s.archetypes.Remove(id)
+}
+
+// Get returns the component of the given type (e.g. `reflect.TypeOf(&Transform{})`) on an entity, if it has one.
+func (e *ECS) Get(id EntityID, componentType reflect.Type) (interface{}, bool) {
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	component, ok := e.components.entities[id][componentType]
+	return component, ok
+}
+
+// Has returns true if the entity exists and has components of all of the given types.
+func (e *ECS) Has(id EntityID, componentTypes ...reflect.Type) bool {
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	components, ok := e.components.entities[id]
+	return ok && hasAll(components, componentTypes)
+}
+
+// Query returns the IDs of all entities that have components of all of the given types, in ascending order.
+func (e *ECS) Query(componentTypes ...reflect.Type) []EntityID {
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	var ids []EntityID
+	for _, arch := range e.components.archetypes.archetypesWith(componentTypes...) {
+		for _, id := range arch.ids {
+			if id != 0 {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// ComponentOf returns an entity's component of type *T, if it has one.
+func ComponentOf[T any](e *ECS, id EntityID) (*T, bool) {
+	component, ok := e.Get(id, reflect.TypeOf((*T)(nil)))
+	if !ok {
+		return nil, false
+	}
+
+	return component.(*T), true
+}
+
+// Each calls fn for every entity with a component of type *A. Entities with the same set of component types are
+// visited in the order they were given them, and the order is the same every time for the same series of events.
+// fn must not add or remove entities or components on the current frame, e.g. with PublishThisFrame.
+func Each[A any](e *ECS, fn func(id EntityID, a *A)) {
+	typeA := reflect.TypeOf((*A)(nil))
+
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	for _, arch := range e.components.archetypes.archetypesWith(typeA) {
+		as := arch.columns[typeA].Interface().([]*A)
+
+		for i, id := range arch.ids {
+			if id != 0 {
+				fn(id, as[i])
+			}
+		}
+	}
+}
+
+// Each2 calls fn for every entity with components of types *A and *B, in the same order as Each.
+func Each2[A, B any](e *ECS, fn func(id EntityID, a *A, b *B)) {
+	typeA, typeB := reflect.TypeOf((*A)(nil)), reflect.TypeOf((*B)(nil))
+
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	for _, arch := range e.components.archetypes.archetypesWith(typeA, typeB) {
+		as := arch.columns[typeA].Interface().([]*A)
+		bs := arch.columns[typeB].Interface().([]*B)
+
+		for i, id := range arch.ids {
+			if id != 0 {
+				fn(id, as[i], bs[i])
+			}
+		}
+	}
+}
+
+// Each3 calls fn for every entity with components of types *A, *B and *C, in the same order as Each.
+func Each3[A, B, C any](e *ECS, fn func(id EntityID, a *A, b *B, c *C)) {
+	typeA, typeB, typeC := reflect.TypeOf((*A)(nil)), reflect.TypeOf((*B)(nil)), reflect.TypeOf((*C)(nil))
+
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	for _, arch := range e.components.archetypes.archetypesWith(typeA, typeB, typeC) {
+		as := arch.columns[typeA].Interface().([]*A)
+		bs := arch.columns[typeB].Interface().([]*B)
+		cs := arch.columns[typeC].Interface().([]*C)
+
+		for i, id := range arch.ids {
+			if id != 0 {
+				fn(id, as[i], bs[i], cs[i])
+			}
+		}
+	}
+}
+
+// AddComponent adds a component to an existing entity on the next frame. This should be a pointer to a struct.
+// If the entity already has a component of the same type, it is replaced.
+func (e *ECS) AddComponent(id EntityID, component interface{}) {
+	e.PublishNextFrame(ComponentAddedEvent{ID: id, Component: component})
+}
+
+// RemoveComponent removes the component of the given type (e.g. `reflect.TypeOf(&Physics{})`) from an existing entity
+// on the next frame.
+func (e *ECS) RemoveComponent(id EntityID, componentType reflect.Type) {
+	e.PublishNextFrame(ComponentRemovedEvent{ID: id, Type: componentType})
+}
+
+// prepareEvent updates the component store for an event that is about to be published, filling in any details of the
+// event that depend on it. Returns false if the event is invalid and should not be published.
+func (e *ECS) prepareEvent(event interface{}) (interface{}, bool) {
+	store := e.components
+
+	// Systems may remove an entity more than once before the removal takes effect, so repeats are quietly dropped.
+	if removed, ok := event.(EntityRemovedEvent); ok && !e.Alive(removed.ID) {
+		return nil, false
+	}
+
+	if targeted, ok := event.(Targeted); ok && !e.Alive(targeted.TargetEntity()) {
+		e.reportFromECS(event, fmt.Errorf("%w: %v", ErrStaleEntity, targeted.TargetEntity()))
+		return nil, false
+	}
+
+	switch event := event.(type) {
+	case EntityAddedEvent:
+		store.mutex.Lock()
+		store.set(event.ID, event.Components)
+		store.mutex.Unlock()
+
+	case ComponentAddedEvent:
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+
+		components, ok := store.entities[event.ID]
+		if !ok {
+			e.reportFromECS(event, fmt.Errorf("cannot add component to nonexistent entity %v", event.ID))
+			return nil, false
+		}
+
+		// Systems may still hold the old map, so the new set of components is stored in a copy.
+		event.Components = copyComponents(components)
+		event.Components[reflect.TypeOf(event.Component)] = event.Component
+		store.set(event.ID, event.Components)
+		return event, true
+
+	case ComponentRemovedEvent:
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+
+		components, ok := store.entities[event.ID]
+		if !ok {
+			e.reportFromECS(event, fmt.Errorf("cannot remove component from nonexistent entity %v", event.ID))
+			return nil, false
+		}
+
+		event.Component, ok = components[event.Type]
+		if !ok {
+			e.reportFromECS(event, fmt.Errorf("entity %v has no %v component to remove", event.ID, event.Type))
+			return nil, false
+		}
+
+		event.Components = copyComponents(components)
+		delete(event.Components, event.Type)
+		store.set(event.ID, event.Components)
+		return event, true
+	}
+
+	return event, true
+}
+
+// finishEvent updates the component store once all systems have handled an event.
+func (e *ECS) finishEvent(event interface{}) {
+	if event, ok := event.(EntityRemovedEvent); ok {
+		e.components.mutex.Lock()
+		e.components.remove(event.ID)
+		e.components.mutex.Unlock()
+
+		e.entityIDs.release(event.ID)
+	}
+}
+
+// hasAll returns true if the component map has all of the given types.
+func hasAll(components map[reflect.Type]interface{}, componentTypes []reflect.Type) bool {
+	for _, t := range componentTypes {
+		if _, ok := components[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// copyComponents returns a shallow copy of a component map.
+func copyComponents(components map[reflect.Type]interface{}) map[reflect.Type]interface{} {
+	result := make(map[reflect.Type]interface{}, len(components)+1)
+	for t, c := range components {
+		result[t] = c
+	}
+	return result
+}
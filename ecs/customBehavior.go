@@ -2,41 +2,72 @@ package ecs
 
 import (
 	"reflect"
+	"runtime"
 )
 
 // BehaviorSystem is a shorthand for simple systems that only keep track of one type of entity, and only perform
 // operations in Update.
 //
-// updater: func(e *ECS, ev EventContainer, delta float64, entityID uint64, componentSet interface{})
+// updater: func(e *ECS, ev EventContainer, delta float64, entityID EntityID, componentSet interface{})
+//
+// BehaviorSystemOf is preferred for new systems, as it checks the updater's signature at compile time.
 func BehaviorSystem(updater interface{}) func(e *ECS) {
 	return func(e *ECS) {
 		componentSetType := reflect.TypeOf(updater).In(4)
 		updaterVal := reflect.ValueOf(updater)
+		name := "BehaviorSystem(" + runtime.FuncForPC(updaterVal.Pointer()).Name() + ")"
 
-		entitiesType := reflect.MapOf(reflect.TypeOf(uint64(0)), componentSetType)
+		entitiesType := reflect.MapOf(reflect.TypeOf(EntityID(0)), componentSetType)
 		entities := reflect.New(entitiesType)
 		entities.Elem().Set(reflect.MakeMap(entitiesType))
 
-		events := e.Subscribe()
+		events := e.SubscribeTo(EntityAddedEvent{}, ComponentAddedEvent{}, ComponentRemovedEvent{},
+			EntityRemovedEvent{}, UpdateBeginEvent{})
 
-		go func() {
-			for ev := range events {
-				switch event := ev.Event.(type) {
-				case EntityAddedEvent:
-					UnpackEntity(event, entities.Interface())
+		go e.HandleEvents(name, events, func(ev EventContainer) {
+			switch event := ev.Event.(type) {
+			case EntityChange:
+				RefreshEntity(event, entities.Interface())
 
-				case EntityRemovedEvent:
-					RemoveEntity(event.ID, entities.Interface())
+			case EntityRemovedEvent:
+				RemoveEntity(event.ID, entities.Interface())
 
-				case UpdateBeginEvent:
-					iter := entities.Elem().MapRange()
-					for iter.Next() {
-						updaterVal.Call([]reflect.Value{reflect.ValueOf(e), reflect.ValueOf(ev), reflect.ValueOf(event.Delta), iter.Key(), iter.Value()})
-					}
+			case UpdateBeginEvent:
+				iter := entities.Elem().MapRange()
+				for iter.Next() {
+					updaterVal.Call([]reflect.Value{reflect.ValueOf(e), reflect.ValueOf(ev), reflect.ValueOf(event.Delta), iter.Key(), iter.Value()})
 				}
+			}
+		})
+	}
+}
+
+// BehaviorSystemOf is a type-safe variant of BehaviorSystem. The component set type T must be a struct whose fields are
+// all pointers to components - this is checked when BehaviorSystemOf is called, rather than failing with a reflection
+// panic once the first entity shows up. The updater is called directly, without any per-call reflection.
+func BehaviorSystemOf[T any](updater func(e *ECS, ev EventContainer, delta float64, entityID EntityID, componentSet T)) func(e *ECS) {
+	mustComponentLayout(reflect.TypeOf((*T)(nil)).Elem())
+	name := "BehaviorSystem(" + runtime.FuncForPC(reflect.ValueOf(updater).Pointer()).Name() + ")"
 
-				ev.Wg.Done()
+	return func(e *ECS) {
+		entities := make(map[EntityID]T)
+
+		events := e.SubscribeTo(EntityAddedEvent{}, ComponentAddedEvent{}, ComponentRemovedEvent{},
+			EntityRemovedEvent{}, UpdateBeginEvent{})
+
+		go e.HandleEvents(name, events, func(ev EventContainer) {
+			switch event := ev.Event.(type) {
+			case EntityChange:
+				RefreshEntityOf(event, entities)
+
+			case EntityRemovedEvent:
+				delete(entities, event.ID)
+
+			case UpdateBeginEvent:
+				for entityID, componentSet := range entities {
+					updater(e, ev, event.Delta, entityID, componentSet)
+				}
 			}
-		}()
+		})
 	}
 }
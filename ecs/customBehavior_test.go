@@ -0,0 +1,78 @@
+package ecs
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// moving is the component set of entities moved by the test behaviour.
+type moving struct {
+	*Position
+	*Velocity
+}
+
+func TestBehaviorSystemOf(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+
+	// The behaviour moves each entity, and records which ones it was called for.
+	var moved []EntityID
+	BehaviorSystemOf(func(e *ECS, ev EventContainer, delta float64, entityID EntityID, set moving) {
+		set.Position.X += set.Velocity.X * delta
+		moved = append(moved, entityID)
+	})(&e)
+
+	// step runs an update, checking which entities the behaviour was called for. Changes to entities are handled after
+	// the update has begun, so an update without any time passing is run first to apply them.
+	step := func(want ...EntityID) {
+		t.Helper()
+
+		if err := e.Step(1, 0); err != nil {
+			t.Fatal(err)
+		}
+		moved = nil
+		if err := e.Step(1, 0.5); err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Slice(moved, func(i, j int) bool { return moved[i] < moved[j] })
+		if !reflect.DeepEqual(moved, want) {
+			t.Errorf("behaviour was called for %v, want %v", moved, want)
+		}
+	}
+
+	runner := &Position{}
+	runnerID := e.AddEntity(runner, &Velocity{X: 2})
+	walker := &Position{}
+	walkerID := e.AddEntity(walker)
+	step(runnerID)
+
+	// Entities are tracked once they have every component, and until they are removed.
+	e.AddComponent(walkerID, &Velocity{X: 4})
+	step(runnerID, walkerID)
+
+	e.RemoveEntity(runnerID)
+	step(walkerID)
+
+	e.RemoveComponent(walkerID, reflect.TypeOf(&Velocity{}))
+	step()
+
+	if runner.X != 2 || walker.X != 4 {
+		t.Errorf("runner moved to %v and walker to %v, want 2 and 4", runner.X, walker.X)
+	}
+	if errs := e.Errors(); len(errs) != 0 {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestBehaviorSystemOfRejectsComponentSet(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a component set with a non-pointer field was accepted")
+		}
+	}()
+
+	BehaviorSystemOf(func(e *ECS, ev EventContainer, delta float64, entityID EntityID, set struct{ Position }) {})
+}
@@ -3,48 +3,133 @@ package ecs
 import (
 	"reflect"
 	"sync"
-	"time"
 )
 
 // ECS Represents an entity component system.
 type ECS struct {
-	EventReceivers  []chan EventContainer
+	EventReceivers  []Subscription
 	CurrentEvents   chan interface{}
 	NextFrameEvents chan interface{}
-	EIDCounter      uint64
 	Running         bool
-	LastFrame       time.Time
+	Clock           Clock            // Determines the delta of each frame when using Run.
+	FixedStep       float64          // If non-zero, Run updates the simulation in fixed steps of this many seconds.
+	MaxFrameTime    float64          // The most time a single frame may feed into the fixed-step accumulator.
+	ErrorPolicy     ErrorPolicy      // Determines what happens when a system reports an error.
+	OnError         func(ErrorEvent) // Called whenever a system reports an error, if set.
+	PanicPolicy     PanicPolicy      // Determines what happens when a system panics.
+	setUp           bool
+	stopped         bool // Set by Stop, after which Step runs no more frames.
+	accumulator     float64
+	updates         uint64                                 // How many updates have begun.
+	receiversByType map[reflect.Type][]chan EventContainer // Caches which receivers accept each type of event.
+	errorLog        *errorLog
+	components      *componentStore
+	entityIDs       *entityAllocator
+}
+
+// EventBatch is a list of events which are published one after another, while only taking up one place in the queue.
+// Next can only hold a few events until the handler sending them returns, so a handler which may publish many events
+// should send them to Next as a single batch.
+type EventBatch []interface{}
+
+// Subscription is a channel which receives events, along with the types of events that should be delivered to it.
+type Subscription struct {
+	Events chan EventContainer
+	Types  map[reflect.Type]bool // The event types to deliver, or nil to deliver every event.
 }
 
 // NewECS initializes and returns a new ECS instance
 func NewECS() ECS {
-	return ECS{[]chan EventContainer{}, make(chan interface{}, 50),
-		make(chan interface{}, 50), 1, false, time.Now()}
+	return ECS{
+		EventReceivers:  []Subscription{},
+		CurrentEvents:   make(chan interface{}, 50),
+		NextFrameEvents: make(chan interface{}, 50),
+		Clock:           NewRealClock(),
+		MaxFrameTime:    0.25,
+		errorLog:        &errorLog{},
+		components:      newComponentStore(),
+		entityIDs:       &entityAllocator{},
+	}
 }
 
-// Subscribe subscribes to events.
+// Subscribe subscribes to all events.
 // It returns a channel which can be used to receive events.
-// The passed event container's `Done` method should be called after each event is fully processed.
+// The passed event container's `Done` method should be called after each event is fully processed - HandleEvents takes
+// care of this automatically.
 func (e *ECS) Subscribe() chan EventContainer {
+	return e.subscribe(nil)
+}
+
+// SubscribeTo subscribes to only the types of the given events, e.g. `SubscribeTo(TransformEvent{}, UpdateBeginEvent{})`.
+// Events of any other type are neither delivered to nor waited on for the returned channel, so systems should prefer
+// this over Subscribe wherever they only handle a known set of events.
+func (e *ECS) SubscribeTo(events ...interface{}) chan EventContainer {
+	types := make(map[reflect.Type]bool)
+	for _, event := range events {
+		types[reflect.TypeOf(event)] = true
+	}
+
+	return e.subscribe(types)
+}
+
+// subscribe adds a new receiver for the given set of event types.
+func (e *ECS) subscribe(types map[reflect.Type]bool) chan EventContainer {
 	echan := make(chan EventContainer, 10)
-	e.EventReceivers = append(e.EventReceivers, echan)
+	e.EventReceivers = append(e.EventReceivers, Subscription{echan, types})
+	e.receiversByType = nil
 	return echan
 }
 
+// receiversFor returns the channels of all subscriptions which accept the given event.
+func (e *ECS) receiversFor(event interface{}) []chan EventContainer {
+	eventType := reflect.TypeOf(event)
+
+	if receivers, ok := e.receiversByType[eventType]; ok {
+		return receivers
+	}
+
+	var receivers []chan EventContainer
+	for _, sub := range e.EventReceivers {
+		if sub.Types == nil || sub.Types[eventType] {
+			receivers = append(receivers, sub.Events)
+		}
+	}
+
+	if e.receiversByType == nil {
+		e.receiversByType = make(map[reflect.Type][]chan EventContainer)
+	}
+	e.receiversByType[eventType] = receivers
+
+	return receivers
+}
+
 // publishNow publishes an event to occur NOW.
 // Using the event container's `Next` channel is the preferred way to do this from systems.
 func (e *ECS) publishNow(event interface{}) {
+	if batch, ok := event.(EventBatch); ok {
+		for _, batchedEvent := range batch {
+			e.publishNow(batchedEvent)
+		}
+		return
+	}
+
+	event, ok := e.prepareEvent(event)
+	if !ok {
+		return
+	}
 
 	next := make(chan interface{}, 50)
+	receivers := e.receiversFor(event)
 
 	var wg sync.WaitGroup
-	wg.Add(len(e.EventReceivers))
+	wg.Add(len(receivers))
 
-	for _, receiver := range e.EventReceivers {
+	for _, receiver := range receivers {
 		receiver <- EventContainer{&wg, event, next}
 	}
 
 	wg.Wait()
+	e.finishEvent(event)
 
 	close(next)
 	for ev := range next {
@@ -63,11 +148,19 @@ func (e *ECS) PublishThisFrame(event interface{}) {
 	e.CurrentEvents <- event
 }
 
-// update runs an update from the loop.
-func (e *ECS) update() {
-	thisFrame := time.Now()
-	delta := thisFrame.Sub(e.LastFrame).Seconds()
-	e.LastFrame = thisFrame
+// setup publishes the SetupEvent if it has not yet been published.
+func (e *ECS) setup() {
+	if e.setUp {
+		return
+	}
+
+	e.setUp = true
+	e.publishNow(SetupEvent{})
+}
+
+// update runs a single frame with the given delta.
+func (e *ECS) update(delta float64) {
+	e.updates++
 
 	// Deliver update before anything else
 	e.publishNow(UpdateBeginEvent{delta})
@@ -82,34 +175,92 @@ func (e *ECS) update() {
 		e.publishNow(event)
 	}
 
+	e.publishNow(LateUpdateEvent{delta})
 	e.publishNow(UpdateEndEvent{delta})
 	close(e.CurrentEvents)
 }
 
+// Updates returns how many updates have begun, including the current one while an update is running. It is only
+// changed between updates, so systems may read it while handling events.
+func (e *ECS) Updates() uint64 {
+	return e.updates
+}
+
+// advance runs as many updates as the given elapsed time calls for, and then a render.
+// Without a FixedStep, this is exactly one update with the elapsed time as its delta. Otherwise, the elapsed time is
+// added to an accumulator which is spent in FixedStep-sized updates, and the remainder is passed on to the render as
+// the interpolation alpha.
+func (e *ECS) advance(elapsed float64) {
+	if e.FixedStep <= 0 {
+		e.update(elapsed)
+		e.publishNow(RenderEvent{1})
+		return
+	}
+
+	// Avoid spiralling when updates take longer than the time they simulate.
+	if e.MaxFrameTime > 0 && elapsed > e.MaxFrameTime {
+		elapsed = e.MaxFrameTime
+	}
+
+	e.accumulator += elapsed
+	for e.accumulator >= e.FixedStep && e.Running {
+		e.update(e.FixedStep)
+		e.accumulator -= e.FixedStep
+	}
+
+	e.publishNow(RenderEvent{e.accumulator / e.FixedStep})
+}
+
 // Run starts the ECS loop. This is a blocking operation.
-func (e *ECS) Run() {
-	e.publishNow(SetupEvent{})
+// The time elapsed each frame is determined by the ECS's Clock. Returns the error which stopped the ECS under
+// ErrorPolicyPanic, if any.
+func (e *ECS) Run() error {
+	e.setup()
 
 	e.Running = true
 	for e.Running {
-		e.update()
+		e.advance(e.Clock.Tick())
 	}
 
+	e.Close()
+	return e.failure()
+}
+
+// Step runs exactly n frames, each with the given delta and followed by a render, and then returns.
+// Unlike Run, this does not depend on the Clock, which makes the simulation reproducible - e.g. for tests or headless
+// servers. The SetupEvent is published before the first frame if it has not been already.
+// Once the ECS is stopped, e.g. by a system or by PanicPolicyStop, no more frames are run. Returns the error which
+// stopped the ECS under ErrorPolicyPanic, if any.
+func (e *ECS) Step(n int, delta float64) error {
+	e.setup()
+
+	for i := 0; i < n && !e.stopped; i++ {
+		e.update(delta)
+		e.publishNow(RenderEvent{1})
+	}
+
+	return e.failure()
+}
+
+// Close shuts down all systems by closing their event channels. Run calls this automatically once stopped, but users
+// of Step should call it when they are finished with the ECS.
+func (e *ECS) Close() {
 	close(e.NextFrameEvents)
 
 	for _, recv := range e.EventReceivers {
-		close(recv)
+		close(recv.Events)
 	}
 }
 
-// Stop stops the ECS loop.
+// Stop stops the ECS loop, along with any call to Step.
 func (e *ECS) Stop() {
 	e.Running = false
+	e.stopped = true
 }
 
 // AddEntity adds an entity with the given components. These should be pointers to structs.
 // Returns the ID of the new entity.
-func (e *ECS) AddEntity(components ...interface{}) uint64 {
+func (e *ECS) AddEntity(components ...interface{}) EntityID {
 
 	tm := make(map[reflect.Type]interface{})
 
@@ -117,8 +268,7 @@ func (e *ECS) AddEntity(components ...interface{}) uint64 {
 		tm[reflect.TypeOf(c)] = c
 	}
 
-	newEID := e.EIDCounter
-	e.EIDCounter += 1
+	newEID := e.entityIDs.allocate()
 
 	e.PublishNextFrame(EntityAddedEvent{
 		newEID,
@@ -129,6 +279,6 @@ func (e *ECS) AddEntity(components ...interface{}) uint64 {
 }
 
 // RemoveEntity removes the given entity from the ECS on the next frame.
-func (e *ECS) RemoveEntity(id uint64) {
+func (e *ECS) RemoveEntity(id EntityID) {
 	e.PublishNextFrame(EntityRemovedEvent{id})
 }
@@ -0,0 +1,128 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+)
+
+// otherEvent is an event which nothing subscribes to by type.
+type otherEvent struct{}
+
+// recordTypes records the types of the events delivered to a subscription, in order.
+func recordTypes(e *ECS, name string, events chan EventContainer) *[]reflect.Type {
+	var types []reflect.Type
+	go e.HandleEvents(name, events, func(ev EventContainer) {
+		types = append(types, reflect.TypeOf(ev.Event))
+	})
+	return &types
+}
+
+func TestSubscribeToFiltersTypes(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+
+	filtered := recordTypes(&e, "FilteredSystem", e.SubscribeTo(pokeEvent{}, UpdateEndEvent{}))
+	everything := recordTypes(&e, "EverythingSystem", e.Subscribe())
+
+	id := e.AddEntity(&Position{})
+	e.PublishNextFrame(otherEvent{})
+	e.PublishNextFrame(pokeEvent{id})
+	if err := e.Step(2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	poke, end := reflect.TypeOf(pokeEvent{}), reflect.TypeOf(UpdateEndEvent{})
+	if want := []reflect.Type{poke, end, end}; !reflect.DeepEqual(*filtered, want) {
+		t.Errorf("subscriber got %v, want %v", *filtered, want)
+	}
+
+	// Every event is still delivered to subscribers which didn't ask for particular types.
+	seen := make(map[reflect.Type]bool)
+	for _, eventType := range *everything {
+		seen[eventType] = true
+	}
+	for _, event := range []interface{}{otherEvent{}, pokeEvent{}, EntityAddedEvent{}, UpdateBeginEvent{}} {
+		if !seen[reflect.TypeOf(event)] {
+			t.Errorf("unfiltered subscriber didn't get %T", event)
+		}
+	}
+
+	if errs := e.Errors(); len(errs) != 0 {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+// scriptedClock ticks through a list of deltas, stopping the ECS once they have all been used.
+type scriptedClock struct {
+	e      *ECS
+	deltas []float64
+}
+
+func (c *scriptedClock) Tick() float64 {
+	if len(c.deltas) == 0 {
+		c.e.Stop()
+		return 0
+	}
+
+	delta := c.deltas[0]
+	c.deltas = c.deltas[1:]
+	return delta
+}
+
+// recordFrames records every update and render, in order.
+func recordFrames(e *ECS) *[]interface{} {
+	var frames []interface{}
+	go e.HandleEvents("RecordingSystem", e.SubscribeTo(UpdateBeginEvent{}, RenderEvent{}), func(ev EventContainer) {
+		frames = append(frames, ev.Event)
+	})
+	return &frames
+}
+
+func TestRunFixedStep(t *testing.T) {
+	e := NewECS()
+	e.ErrorPolicy = ErrorPolicyCollect
+	e.FixedStep = 0.25
+	e.MaxFrameTime = 1
+	e.Clock = &scriptedClock{&e, []float64{
+		0.625,  // Two updates, leaving half a step.
+		0.0625, // No updates, leaving three quarters of a step.
+		5,      // Only a second counts, so four updates.
+	}}
+	frames := recordFrames(&e)
+
+	if err := e.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	step := UpdateBeginEvent{0.25}
+	want := []interface{}{
+		step, step, RenderEvent{0.5},
+		RenderEvent{0.75},
+		step, step, step, step, RenderEvent{0.75},
+		RenderEvent{0.75}, // The ECS renders once more as it stops.
+	}
+	if !reflect.DeepEqual(*frames, want) {
+		t.Errorf("got frames %v, want %v", *frames, want)
+	}
+	if errs := e.Errors(); len(errs) != 0 {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestStepIgnoresFixedStep(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+	e.FixedStep = 0.25
+	frames := recordFrames(&e)
+
+	if err := e.Step(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []interface{}{UpdateBeginEvent{1}, RenderEvent{1}, UpdateBeginEvent{1}, RenderEvent{1}}
+	if !reflect.DeepEqual(*frames, want) {
+		t.Errorf("got frames %v, want %v", *frames, want)
+	}
+}
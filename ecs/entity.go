@@ -0,0 +1,157 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// EntityID identifies an entity. The lower 32 bits are an index, which is reused once the entity has been removed, and
+// the upper 32 bits are a generation, which changes every time the index is reused. This means that an ID held on to
+// after its entity has been removed will never refer to a different entity - see Alive.
+// The zero EntityID never refers to an entity, and is used to mean 'no entity'.
+type EntityID uint64
+
+// NewEntityID returns the EntityID with the given index and generation.
+func NewEntityID(index uint32, generation uint32) EntityID {
+	return EntityID(generation)<<32 | EntityID(index)
+}
+
+// Index returns the index part of the ID, which may be shared with removed entities.
+func (id EntityID) Index() uint32 {
+	return uint32(id)
+}
+
+// Generation returns the generation part of the ID, which distinguishes it from removed entities with the same index.
+func (id EntityID) Generation() uint32 {
+	return uint32(id >> 32)
+}
+
+// String formats the ID as index:generation.
+func (id EntityID) String() string {
+	return fmt.Sprintf("%d:%d", id.Index(), id.Generation())
+}
+
+// Targeted is implemented by events which act upon one particular entity. Such events are rejected with
+// ErrStaleEntity, rather than being published, if the entity they target is no longer alive.
+type Targeted interface {
+	TargetEntity() EntityID
+}
+
+// ErrStaleEntity is reported when an event targets an entity which has already been removed.
+var ErrStaleEntity = errors.New("entity is no longer alive")
+
+// entityAllocator hands out entity IDs, reusing the indices of removed entities with a new generation.
+type entityAllocator struct {
+	mutex       sync.Mutex
+	generations []uint32 // The current generation of each index. Index 0 is never used.
+	live        []bool   // Whether each index currently belongs to an entity.
+	free        []uint32 // Indices of removed entities, available for reuse.
+}
+
+// allocate returns a fresh EntityID.
+func (a *entityAllocator) allocate() EntityID {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.generations) == 0 {
+		a.generations = append(a.generations, 0)
+		a.live = append(a.live, false)
+	}
+
+	if len(a.free) > 0 {
+		index := a.free[len(a.free)-1]
+		a.free = a.free[:len(a.free)-1]
+		a.live[index] = true
+		return NewEntityID(index, a.generations[index])
+	}
+
+	a.generations = append(a.generations, 0)
+	a.live = append(a.live, true)
+	return NewEntityID(uint32(len(a.generations)-1), 0)
+}
+
+// release marks the entity as dead, making its index available for reuse under the next generation.
+func (a *entityAllocator) release(id EntityID) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.alive(id) {
+		return
+	}
+
+	a.generations[id.Index()]++
+	a.live[id.Index()] = false
+	a.free = append(a.free, id.Index())
+}
+
+// reserve marks the given IDs as in use, so that entities can be restored with their original IDs. Fails without
+// reserving anything if any of the indices are already in use.
+func (a *entityAllocator) reserve(ids []EntityID) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	seen := make(map[uint32]bool, len(ids))
+	for _, id := range ids {
+		index := id.Index()
+		if index == 0 {
+			return errors.New("cannot reserve the zero entity ID")
+		}
+		if int(index) < len(a.live) && a.live[index] {
+			return fmt.Errorf("entity index %d is already in use", index)
+		}
+		if seen[index] {
+			return fmt.Errorf("entity index %d is reserved twice", index)
+		}
+		seen[index] = true
+	}
+
+	if len(a.generations) == 0 {
+		a.generations = append(a.generations, 0)
+		a.live = append(a.live, false)
+	}
+
+	for _, id := range ids {
+		index := id.Index()
+
+		// Any indices skipped over are free for later use.
+		for int(index) >= len(a.generations) {
+			a.free = append(a.free, uint32(len(a.generations)))
+			a.generations = append(a.generations, 0)
+			a.live = append(a.live, false)
+		}
+
+		a.generations[index] = id.Generation()
+		a.live[index] = true
+	}
+
+	// Drop the reserved indices from the free list.
+	free := a.free[:0]
+	for _, index := range a.free {
+		if !a.live[index] {
+			free = append(free, index)
+		}
+	}
+	a.free = free
+
+	return nil
+}
+
+// alive returns true if the entity has not been released. The mutex must be held.
+func (a *entityAllocator) alive(id EntityID) bool {
+	index := id.Index()
+	if index == 0 || int(index) >= len(a.generations) {
+		return false
+	}
+
+	return a.live[index] && a.generations[index] == id.Generation()
+}
+
+// Alive returns true if the given ID refers to an entity which has been added and not yet removed.
+// Entities are alive from the moment AddEntity returns their ID, until their EntityRemovedEvent has been handled.
+func (e *ECS) Alive(id EntityID) bool {
+	e.entityIDs.mutex.Lock()
+	defer e.entityIDs.mutex.Unlock()
+
+	return e.entityIDs.alive(id)
+}
@@ -0,0 +1,113 @@
+package ecs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pokeEvent is an event aimed at a single entity.
+type pokeEvent struct{ ID EntityID }
+
+func (p pokeEvent) TargetEntity() EntityID {
+	return p.ID
+}
+
+// countPokes counts the pokes that reach systems.
+func countPokes(e *ECS) *int {
+	var pokes int
+	go e.HandleEvents("PokedSystem", e.SubscribeTo(pokeEvent{}), func(ev EventContainer) {
+		pokes++
+	})
+	return &pokes
+}
+
+// removedEntity adds an entity and removes it again, returning its ID.
+func removedEntity(t *testing.T, e *ECS) EntityID {
+	t.Helper()
+
+	id := e.AddEntity(&Position{})
+	if err := e.Step(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	e.RemoveEntity(id)
+	if err := e.Step(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func TestEntityIndexReuse(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+
+	removed := removedEntity(t, &e)
+	if e.Alive(removed) {
+		t.Fatalf("%v is still alive after being removed", removed)
+	}
+
+	reused := e.AddEntity(&Position{})
+	if reused.Index() != removed.Index() || reused.Generation() != removed.Generation()+1 {
+		t.Fatalf("got %v after removing %v, want the same index in the next generation", reused, removed)
+	}
+	if !e.Alive(reused) || e.Alive(removed) {
+		t.Errorf("alive: new %v is %v and old %v is %v, want only the new one", reused, e.Alive(reused), removed,
+			e.Alive(removed))
+	}
+
+	if errs := e.Errors(); len(errs) != 0 {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestStaleEventRejected(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+	pokes := countPokes(&e)
+
+	removed := removedEntity(t, &e)
+	reused := e.AddEntity(&Position{})
+
+	// The old ID shares its index with the new entity, which mustn't be poked in its place.
+	e.PublishNextFrame(pokeEvent{removed})
+	e.PublishNextFrame(pokeEvent{reused})
+	if err := e.Step(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if *pokes != 1 {
+		t.Errorf("%d pokes were delivered, want only the one to the live entity", *pokes)
+	}
+
+	errs := e.Errors()
+	if len(errs) != 1 || !errors.Is(errs[0].Cause, ErrStaleEntity) {
+		t.Fatalf("got errors %v, want one stale entity", errs)
+	}
+	if !strings.Contains(errs[0].Error(), removed.String()) {
+		t.Errorf("error %q doesn't name %v", errs[0], removed)
+	}
+}
+
+func TestStaleEventUnderErrorPolicyPanic(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	removed := removedEntity(t, &e)
+
+	e.ErrorPolicy = ErrorPolicyPanic
+	updates := countUpdates(&e)
+
+	e.PublishNextFrame(pokeEvent{removed})
+	err := e.Step(3, 0)
+
+	var errorEvent ErrorEvent
+	if !errors.As(err, &errorEvent) || !errors.Is(errorEvent.Cause, ErrStaleEntity) {
+		t.Fatalf("got %v, want the stale entity error", err)
+	}
+	if *updates != 1 {
+		t.Errorf("got %d updates, want the ECS to stop after the first", *updates)
+	}
+}
@@ -0,0 +1,104 @@
+package ecs
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ErrorEvent describes a problem that a system encountered while handling an event.
+type ErrorEvent struct {
+	System string      // The name of the system which reported the error.
+	Event  interface{} // The event being handled when the error occurred.
+	Cause  error       // The underlying error.
+}
+
+// Error formats the error event as a readable message.
+func (e ErrorEvent) Error() string {
+	return fmt.Sprintf("%s: %v (handling %T)", e.System, e.Cause, e.Event)
+}
+
+// ErrorPolicy determines what the ECS does when a system reports an error.
+type ErrorPolicy int
+
+const (
+	ErrorPolicyLog     ErrorPolicy = iota // Log the error and continue.
+	ErrorPolicyPanic                      // Panic with the error.
+	ErrorPolicyCollect                    // Silently store the error, to be retrieved later with Errors. Useful for tests.
+)
+
+// errorLog holds errors collected under ErrorPolicyCollect, and the error which stopped the ECS under ErrorPolicyPanic.
+type errorLog struct {
+	mutex   sync.Mutex
+	errors  []ErrorEvent
+	failure error
+}
+
+// ReportError reports a problem that a system encountered while handling an event, instead of exiting the process.
+// The error is passed to OnError if it is set, and then handled according to the ECS's ErrorPolicy.
+// This is safe to call from any system. Under ErrorPolicyPanic, it panics with the error, which HandleEvents recovers
+// from by stopping the ECS, and the error is returned by Step or Run.
+func (e *ECS) ReportError(system string, event interface{}, cause error) {
+	if err := e.report(ErrorEvent{system, event, cause}); err != nil {
+		panic(err)
+	}
+}
+
+// reportFromECS reports a problem that the ECS itself found while publishing an event. This happens outside of any
+// handler, so under ErrorPolicyPanic the ECS is stopped with the error rather than panicking, and the error is returned
+// by Step or Run just as if a handler had reported it.
+func (e *ECS) reportFromECS(event interface{}, cause error) {
+	if err := e.report(ErrorEvent{"ECS", event, cause}); err != nil {
+		e.fail(err)
+	}
+}
+
+// report passes the error to OnError and handles it according to the ECS's ErrorPolicy, returning it if it should
+// panic.
+func (e *ECS) report(errorEvent ErrorEvent) error {
+	if e.OnError != nil {
+		e.OnError(errorEvent)
+	}
+
+	switch e.ErrorPolicy {
+	case ErrorPolicyLog:
+		log.Println(errorEvent)
+
+	case ErrorPolicyPanic:
+		return errorEvent
+
+	case ErrorPolicyCollect:
+		e.errorLog.mutex.Lock()
+		e.errorLog.errors = append(e.errorLog.errors, errorEvent)
+		e.errorLog.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// fail stops the ECS with the given error, which is returned by Step or Run. Only the first error is kept.
+func (e *ECS) fail(err error) {
+	e.errorLog.mutex.Lock()
+	if e.errorLog.failure == nil {
+		e.errorLog.failure = err
+	}
+	e.errorLog.mutex.Unlock()
+
+	e.Stop()
+}
+
+// failure returns the error the ECS was stopped with, if any.
+func (e *ECS) failure() error {
+	e.errorLog.mutex.Lock()
+	defer e.errorLog.mutex.Unlock()
+
+	return e.errorLog.failure
+}
+
+// Errors returns all errors collected under ErrorPolicyCollect so far.
+func (e *ECS) Errors() []ErrorEvent {
+	e.errorLog.mutex.Lock()
+	defer e.errorLog.mutex.Unlock()
+
+	return append([]ErrorEvent(nil), e.errorLog.errors...)
+}
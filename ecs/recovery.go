@@ -0,0 +1,73 @@
+package ecs
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy determines what the ECS does when a system panics while handling an event.
+type PanicPolicy int
+
+const (
+	PanicPolicyDisableSystem PanicPolicy = iota // Stop handling events in the faulty system, but keep the ECS running.
+	PanicPolicyStop                             // Disable the faulty system and stop the ECS after the current frame.
+)
+
+// PanicError is reported when a system panics while handling an event.
+type PanicError struct {
+	Value interface{} // The value passed to panic.
+	Stack []byte      // The stack trace of the panicking goroutine.
+}
+
+// Error formats the panic value and stack trace.
+func (p PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", p.Value, p.Stack)
+}
+
+// HandleEvents passes each event received from the given channel to the given handler, and marks it as done once the
+// handler returns. If the handler panics, the event is still marked as done so that the ECS does not deadlock, the
+// panic is reported as through ReportError, and the ECS's PanicPolicy is applied. Under ErrorPolicyPanic, the panic
+// stops the ECS and is returned by Step or Run, rather than crashing the system's goroutine.
+// This blocks until the channel is closed, so systems will generally run it in a goroutine.
+func (e *ECS) HandleEvents(system string, events chan EventContainer, handler func(ev EventContainer)) {
+	disabled := false
+
+	for ev := range events {
+		if disabled {
+			ev.Done()
+			continue
+		}
+
+		disabled = !e.handleEvent(system, ev, handler)
+	}
+}
+
+// handleEvent runs the handler on a single event, returning false if it panicked.
+func (e *ECS) handleEvent(system string, ev EventContainer, handler func(ev EventContainer)) (ok bool) {
+	defer ev.Done()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+
+			if e.PanicPolicy == PanicPolicyStop {
+				e.Stop()
+			}
+
+			// Errors reported by the handler under ErrorPolicyPanic have been reported already.
+			var err error
+			if reported, ok := r.(ErrorEvent); ok && e.ErrorPolicy == ErrorPolicyPanic {
+				err = reported
+			} else {
+				err = e.report(ErrorEvent{system, ev.Event, PanicError{r, debug.Stack()}})
+			}
+
+			if err != nil {
+				e.fail(err)
+			}
+		}
+	}()
+
+	handler(ev)
+	return true
+}
@@ -0,0 +1,146 @@
+package ecs
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+)
+
+// errBroken is reported by brokenSystem.
+var errBroken = errors.New("broken")
+
+// brokenSystem panics, or reports errBroken if report is set, in the first update it handles.
+func brokenSystem(e *ECS, report bool) *int {
+	var handled int
+	go e.HandleEvents("BrokenSystem", e.SubscribeTo(UpdateBeginEvent{}), func(ev EventContainer) {
+		handled++
+		if report {
+			e.ReportError("BrokenSystem", ev.Event, errBroken)
+		} else {
+			panic("oh no")
+		}
+	})
+	return &handled
+}
+
+// countUpdates counts the updates that a healthy system sees.
+func countUpdates(e *ECS) *int {
+	var updates int
+	go e.HandleEvents("CountingSystem", e.SubscribeTo(UpdateBeginEvent{}), func(ev EventContainer) {
+		updates++
+	})
+	return &updates
+}
+
+func TestPanicPolicyDisableSystem(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+
+	handled := brokenSystem(&e, false)
+	updates := countUpdates(&e)
+
+	if err := e.Step(3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if *handled != 1 || *updates != 3 {
+		t.Errorf("broken system handled %d updates and healthy system %d, want 1 and 3", *handled, *updates)
+	}
+
+	errs := e.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one", errs)
+	}
+	if panicked, ok := errs[0].Cause.(PanicError); !ok || panicked.Value != "oh no" || errs[0].System != "BrokenSystem" {
+		t.Errorf("got error %v, want the broken system's panic", errs[0])
+	}
+}
+
+func TestPanicPolicyStop(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyCollect
+	e.PanicPolicy = PanicPolicyStop
+
+	brokenSystem(&e, false)
+	updates := countUpdates(&e)
+
+	if err := e.Step(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	e.Step(3, 1)
+
+	if *updates != 1 || len(e.Errors()) != 1 {
+		t.Errorf("got %d updates and errors %v, want the ECS to stop after the first update", *updates, e.Errors())
+	}
+}
+
+func TestErrorPolicyPanicReturnsPanic(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyPanic
+
+	var reported []ErrorEvent
+	e.OnError = func(err ErrorEvent) { reported = append(reported, err) }
+
+	brokenSystem(&e, false)
+	updates := countUpdates(&e)
+
+	err := e.Step(3, 1)
+
+	var errorEvent ErrorEvent
+	if !errors.As(err, &errorEvent) {
+		t.Fatalf("got %v, want an ErrorEvent", err)
+	}
+	if panicked, ok := errorEvent.Cause.(PanicError); !ok || panicked.Value != "oh no" {
+		t.Errorf("got %v, want the broken system's panic", err)
+	}
+	if *updates != 1 || len(reported) != 1 {
+		t.Errorf("got %d updates and %d errors passed to OnError, want 1 and 1", *updates, len(reported))
+	}
+}
+
+func TestErrorPolicyPanicReturnsReportedError(t *testing.T) {
+	e := NewECS()
+	defer e.Close()
+	e.ErrorPolicy = ErrorPolicyPanic
+
+	var reported []ErrorEvent
+	e.OnError = func(err ErrorEvent) { reported = append(reported, err) }
+
+	brokenSystem(&e, true)
+	updates := countUpdates(&e)
+
+	err := e.Step(3, 1)
+
+	var errorEvent ErrorEvent
+	if !errors.As(err, &errorEvent) || errorEvent.Cause != errBroken {
+		t.Fatalf("got %v, want the reported error", err)
+	}
+	if *updates != 1 || len(reported) != 1 {
+		t.Errorf("got %d updates and %d errors passed to OnError, want 1 and 1", *updates, len(reported))
+	}
+}
+
+func TestErrorPolicyLog(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	e := NewECS()
+	defer e.Close()
+
+	brokenSystem(&e, true)
+	updates := countUpdates(&e)
+
+	if err := e.Step(3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(logged.Bytes(), []byte("BrokenSystem: broken")) || *updates != 3 || len(e.Errors()) != 0 {
+		t.Errorf("logged %q with %d updates, want the error logged and 3 updates", logged.String(), *updates)
+	}
+}
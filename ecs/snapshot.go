@@ -0,0 +1,164 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// Resolver converts a component to and from a serializable representation. This is needed for components with fields
+// that cannot be serialized directly, such as sprites or functions.
+type Resolver struct {
+	Save func(component interface{}) (interface{}, error) // Returns a value which can be encoded as JSON.
+	Load func(data json.RawMessage) (interface{}, error)  // Returns a pointer to a new component.
+}
+
+// ComponentRegistry maps component types to stable names, so that entities can be saved and loaded.
+type ComponentRegistry struct {
+	byName map[string]registeredComponent
+	byType map[reflect.Type]registeredComponent
+}
+
+// registeredComponent describes how to save and load one type of component.
+type registeredComponent struct {
+	name          string
+	componentType reflect.Type
+	resolver      Resolver
+}
+
+// NewComponentRegistry initializes and returns an empty ComponentRegistry.
+func NewComponentRegistry() *ComponentRegistry {
+	return &ComponentRegistry{
+		byName: make(map[string]registeredComponent),
+		byType: make(map[reflect.Type]registeredComponent),
+	}
+}
+
+// Register registers a component type under the given name, saving and loading it as plain JSON.
+// component should be a pointer to a struct of the registered type, e.g. `&Transform{}`.
+func (r *ComponentRegistry) Register(name string, component interface{}) {
+	componentType := reflect.TypeOf(component)
+
+	r.RegisterResolver(name, component, Resolver{
+		Save: func(component interface{}) (interface{}, error) {
+			return component, nil
+		},
+		Load: func(data json.RawMessage) (interface{}, error) {
+			component := reflect.New(componentType.Elem()).Interface()
+			err := json.Unmarshal(data, component)
+			return component, err
+		},
+	})
+}
+
+// RegisterResolver registers a component type under the given name, saving and loading it with the given resolver.
+func (r *ComponentRegistry) RegisterResolver(name string, component interface{}, resolver Resolver) {
+	registered := registeredComponent{name, reflect.TypeOf(component), resolver}
+	r.byName[name] = registered
+	r.byType[registered.componentType] = registered
+}
+
+// SystemOwned is a marker component for entities which a system creates for itself, such as labels added during setup.
+// Save leaves these entities out, since the system creates them again when the loaded game is set up.
+type SystemOwned struct{}
+
+// Snapshot is the serialized form of a set of entities.
+type Snapshot struct {
+	Entities []EntitySnapshot `json:"entities"`
+}
+
+// EntitySnapshot is the serialized form of a single entity, keyed by registered component names.
+type EntitySnapshot struct {
+	ID         EntityID                   `json:"id"`
+	Components map[string]json.RawMessage `json:"components"`
+}
+
+// Save writes every live entity, along with its components, to the given writer as JSON.
+// Components of types which aren't in the registry are left out, as are entities with no registered components at all
+// and SystemOwned entities. This should only be called while systems are idle, e.g. between calls to Step.
+func (e *ECS) Save(w io.Writer, registry *ComponentRegistry) error {
+	e.components.mutex.RLock()
+	defer e.components.mutex.RUnlock()
+
+	var snapshot Snapshot
+
+	for id, components := range e.components.entities {
+		if _, owned := components[reflect.TypeOf(&SystemOwned{})]; owned {
+			continue
+		}
+
+		entity := EntitySnapshot{ID: id, Components: make(map[string]json.RawMessage)}
+
+		for componentType, component := range components {
+			registered, ok := registry.byType[componentType]
+			if !ok {
+				continue
+			}
+
+			saved, err := registered.resolver.Save(component)
+			if err != nil {
+				return fmt.Errorf("saving %s of entity %v: %w", registered.name, id, err)
+			}
+
+			entity.Components[registered.name], err = json.Marshal(saved)
+			if err != nil {
+				return fmt.Errorf("saving %s of entity %v: %w", registered.name, id, err)
+			}
+		}
+
+		if len(entity.Components) > 0 {
+			snapshot.Entities = append(snapshot.Entities, entity)
+		}
+	}
+
+	sort.Slice(snapshot.Entities, func(i, j int) bool { return snapshot.Entities[i].ID < snapshot.Entities[j].ID })
+
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Load reads entities written by Save and adds them on the next frame, with their original IDs. Since IDs are kept,
+// references between entities, such as transform parents, are restored as well.
+// Nothing is added if any entity fails to load, or if any of the saved IDs are already in use.
+func (e *ECS) Load(r io.Reader, registry *ComponentRegistry) error {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	loaded := make(EventBatch, 0, len(snapshot.Entities))
+
+	for _, entity := range snapshot.Entities {
+		event := EntityAddedEvent{entity.ID, make(map[reflect.Type]interface{})}
+
+		for name, data := range entity.Components {
+			registered, ok := registry.byName[name]
+			if !ok {
+				return fmt.Errorf("loading entity %v: unknown component %q", entity.ID, name)
+			}
+
+			component, err := registered.resolver.Load(data)
+			if err != nil {
+				return fmt.Errorf("loading %s of entity %v: %w", name, entity.ID, err)
+			}
+
+			event.Components[reflect.TypeOf(component)] = component
+		}
+
+		loaded = append(loaded, event)
+	}
+
+	ids := make([]EntityID, len(snapshot.Entities))
+	for i, entity := range snapshot.Entities {
+		ids[i] = entity.ID
+	}
+
+	if err := e.entityIDs.reserve(ids); err != nil {
+		return err
+	}
+
+	// The entities are published as a single batch, so that large saves can't overflow the event queue.
+	e.PublishNextFrame(loaded)
+	return nil
+}
@@ -1,19 +1,43 @@
 package ecs
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
 
 // UnpackEntity takes an event and adds it to the given map if its components match the fields in the map's value struct.
-// entityMap should be a pointer to a map[uint64]eCollection, where eCollection is some struct type (generally with a
+// entityMap should be a pointer to a map[EntityID]eCollection, where eCollection is some struct type (generally with a
 // name beginning with e for entity) which represents some subset of an entity's components that are used by the system.
 // This also returns a pointer to the the added structure, or nil if the entity didn't meet requirements.
 func UnpackEntity(event EntityAddedEvent, entityMap interface{}) interface{} {
+	return unpackComponents(event.ID, event.Components, entityMap)
+}
+
+// RefreshEntity re-evaluates whether an entity belongs in the given map after any change to its set of components,
+// adding, updating or removing its entry as appropriate. This is how systems start and stop tracking entities as
+// components are added and removed at runtime.
+// entityMap is the same as for UnpackEntity. Returns a pointer to the entity's entry, or nil if it is not tracked.
+func RefreshEntity(change EntityChange, entityMap interface{}) interface{} {
+	id, components := change.ChangedEntity()
+
+	entry := unpackComponents(id, components, entityMap)
+	if entry == nil {
+		RemoveEntity(id, entityMap)
+	}
+
+	return entry
+}
+
+// unpackComponents adds an entity with the given components to the given map if they match its value struct.
+func unpackComponents(id EntityID, components map[reflect.Type]interface{}, entityMap interface{}) interface{} {
 	structType := reflect.TypeOf(entityMap).Elem().Elem()
 
 	newEntry := reflect.New(structType)
 
 	// Populate fields on the new entry. Abort if one of the required components does not exist on it.
 	for i := 0; i < structType.NumField(); i++ {
-		com, ok := event.Components[structType.Field(i).Type]
+		com, ok := components[structType.Field(i).Type]
 		if !ok {
 			return nil
 		}
@@ -22,13 +46,93 @@ func UnpackEntity(event EntityAddedEvent, entityMap interface{}) interface{} {
 	}
 
 	// Store the value in the map and return it
-	reflect.ValueOf(entityMap).Elem().SetMapIndex(reflect.ValueOf(event.ID), newEntry.Elem())
+	reflect.ValueOf(entityMap).Elem().SetMapIndex(reflect.ValueOf(id), newEntry.Elem())
 	return newEntry.Interface()
 }
 
-// RemoveEntity Removes an entity from the given map of entity IDs (uint64) if it exists. Essentially undoes the work
+// RemoveEntity Removes an entity from the given map of entity IDs if it exists. Essentially undoes the work
 // of UnpackEntity.
-func RemoveEntity(eid uint64, entityMap interface{}) {
+func RemoveEntity(eid EntityID, entityMap interface{}) {
 	eMap := reflect.ValueOf(entityMap).Elem()
 	eMap.SetMapIndex(reflect.ValueOf(eid), reflect.Value{})
 }
+
+// componentLayouts caches the field types of component set structs, keyed by the struct type.
+var componentLayouts sync.Map
+
+// componentLayout returns the component types of the fields of a component set struct, or an error if it is not a
+// valid component set: a struct whose fields are all exported pointers.
+func componentLayout(structType reflect.Type) ([]reflect.Type, error) {
+	if layout, ok := componentLayouts.Load(structType); ok {
+		return layout.([]reflect.Type), nil
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("component set %v is not a struct", structType)
+	}
+
+	layout := make([]reflect.Type, structType.NumField())
+	for i := range layout {
+		field := structType.Field(i)
+
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("component set %v has unexported field %s", structType, field.Name)
+		}
+		if field.Type.Kind() != reflect.Ptr {
+			return nil, fmt.Errorf("component set %v has field %s which is not a pointer to a component", structType, field.Name)
+		}
+
+		layout[i] = field.Type
+	}
+
+	componentLayouts.Store(structType, layout)
+	return layout, nil
+}
+
+// mustComponentLayout is like componentLayout, but panics if the component set is invalid.
+func mustComponentLayout(structType reflect.Type) []reflect.Type {
+	layout, err := componentLayout(structType)
+	if err != nil {
+		panic(err)
+	}
+	return layout
+}
+
+// UnpackEntityOf is a type-safe variant of UnpackEntity, which adds an entity to the given map if its components match
+// the fields of T. Returns a pointer to the added entry, or nil if the entity didn't meet requirements.
+func UnpackEntityOf[T any](event EntityAddedEvent, entityMap map[EntityID]T) *T {
+	return unpackComponentsOf(event.ID, event.Components, entityMap)
+}
+
+// RefreshEntityOf is a type-safe variant of RefreshEntity, which adds, updates or removes an entity's entry in the
+// given map after any change to its set of components. Returns a pointer to the entry, or nil if it is not tracked.
+func RefreshEntityOf[T any](change EntityChange, entityMap map[EntityID]T) *T {
+	id, components := change.ChangedEntity()
+
+	entry := unpackComponentsOf(id, components, entityMap)
+	if entry == nil {
+		delete(entityMap, id)
+	}
+
+	return entry
+}
+
+// unpackComponentsOf adds an entity with the given components to the given map if they match the fields of T.
+func unpackComponentsOf[T any](id EntityID, components map[reflect.Type]interface{}, entityMap map[EntityID]T) *T {
+	layout := mustComponentLayout(reflect.TypeOf((*T)(nil)).Elem())
+
+	var entry T
+	entryVal := reflect.ValueOf(&entry).Elem()
+
+	for i, componentType := range layout {
+		com, ok := components[componentType]
+		if !ok {
+			return nil
+		}
+
+		entryVal.Field(i).Set(reflect.ValueOf(com))
+	}
+
+	entityMap[id] = entry
+	return &entry
+}
@@ -1,8 +1,17 @@
 module github.com/emctague/go-loopy
 
-go 1.14
+go 1.18
 
 require (
 	github.com/faiface/pixel v0.9.0
 	golang.org/x/image v0.0.0-20200430140353-33d19683fad8
 )
+
+require (
+	github.com/faiface/glhf v0.0.0-20181018222622-82a6317ac380 // indirect
+	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3 // indirect
+	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7 // indirect
+	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1 // indirect
+	github.com/go-gl/mathgl v0.0.0-20190416160123-c4601bc793c7 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+)
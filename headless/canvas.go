@@ -0,0 +1,225 @@
+package headless
+
+import (
+	"github.com/faiface/pixel"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+)
+
+// Canvas is a pixel.BasicTarget which rasterizes triangles in software into an image.RGBA, without a window or GPU.
+// Sprites and text are drawn onto it exactly as they would be onto a pixelgl window, including texture intensity,
+// color masks and matrices, although there is no anti-aliasing and pictures are always sampled without smoothing.
+type Canvas struct {
+	image  *image.RGBA
+	matrix pixel.Matrix
+	mask   pixel.RGBA
+}
+
+// NewCanvas returns a transparent canvas of the given size, with its origin at the bottom-left as in pixelgl.
+func NewCanvas(width int, height int) *Canvas {
+	return &Canvas{
+		image:  image.NewRGBA(image.Rect(0, 0, width, height)),
+		matrix: pixel.IM,
+		mask:   pixel.Alpha(1),
+	}
+}
+
+// Image returns the image which the canvas draws to. It is drawn to in place, so it should not be used while systems
+// may be drawing.
+func (c *Canvas) Image() *image.RGBA {
+	return c.image
+}
+
+// Bounds returns the bounds of the canvas in pixel coordinates.
+func (c *Canvas) Bounds() pixel.Rect {
+	size := c.image.Bounds().Size()
+	return pixel.R(0, 0, float64(size.X), float64(size.Y))
+}
+
+// Clear fills the whole canvas with the given color.
+func (c *Canvas) Clear(clearColor color.Color) {
+	rgba := color.RGBAModel.Convert(clearColor).(color.RGBA)
+	for i := 0; i < len(c.image.Pix); i += 4 {
+		c.image.Pix[i], c.image.Pix[i+1], c.image.Pix[i+2], c.image.Pix[i+3] = rgba.R, rgba.G, rgba.B, rgba.A
+	}
+}
+
+// WritePNG encodes the canvas's current contents as a PNG.
+func (c *Canvas) WritePNG(w io.Writer) error {
+	return png.Encode(w, c.image)
+}
+
+// SavePNG writes the canvas's current contents to a PNG file at the given path.
+func (c *Canvas) SavePNG(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.WritePNG(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// SetMatrix sets the matrix which every drawn point is projected by.
+func (c *Canvas) SetMatrix(matrix pixel.Matrix) {
+	c.matrix = matrix
+}
+
+// SetColorMask sets the color which every drawn pixel is multiplied by.
+func (c *Canvas) SetColorMask(mask color.Color) {
+	if mask == nil {
+		mask = pixel.Alpha(1)
+	}
+	c.mask = pixel.ToRGBA(mask)
+}
+
+// MakeTriangles returns a copy of the triangles which draws onto this canvas.
+func (c *Canvas) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	data := pixel.MakeTrianglesData(t.Len())
+	data.Update(t)
+	return &canvasTriangles{data, c}
+}
+
+// MakePicture returns a copy of the picture which draws onto this canvas.
+func (c *Canvas) MakePicture(picture pixel.Picture) pixel.TargetPicture {
+	data, ok := picture.(*pixel.PictureData)
+	if !ok {
+		data = pixel.PictureDataFromPicture(picture)
+	}
+	return &canvasPicture{data, c}
+}
+
+// canvasTriangles are triangles which can be drawn onto a Canvas.
+type canvasTriangles struct {
+	*pixel.TrianglesData
+	canvas *Canvas
+}
+
+// Slice returns a sub-range of the triangles, sharing the same vertices.
+func (t *canvasTriangles) Slice(i, j int) pixel.Triangles {
+	return &canvasTriangles{t.TrianglesData.Slice(i, j).(*pixel.TrianglesData), t.canvas}
+}
+
+// Copy returns an independent copy of the triangles.
+func (t *canvasTriangles) Copy() pixel.Triangles {
+	return &canvasTriangles{t.TrianglesData.Copy().(*pixel.TrianglesData), t.canvas}
+}
+
+// Draw draws the triangles onto the canvas using only their vertex colors.
+func (t *canvasTriangles) Draw() {
+	t.canvas.fill(t.TrianglesData, nil)
+}
+
+// canvasPicture is a picture which can be drawn onto a Canvas.
+type canvasPicture struct {
+	*pixel.PictureData
+	canvas *Canvas
+}
+
+// Draw draws the triangles onto the canvas, textured with the picture.
+func (p *canvasPicture) Draw(t pixel.TargetTriangles) {
+	p.canvas.fill(t.(*canvasTriangles).TrianglesData, p.PictureData)
+}
+
+// fill rasterizes each triangle, sampling every pixel whose center lies within it. Pixels exactly on an edge belong to
+// only one of the triangles sharing that edge, so that quads made of two triangles don't have a visible seam.
+func (c *Canvas) fill(triangles *pixel.TrianglesData, picture *pixel.PictureData) {
+	vertices := *triangles
+	bounds := c.Bounds()
+
+	for i := 0; i+2 < len(vertices); i += 3 {
+		tri := pixel.TrianglesData{vertices[i], vertices[i+1], vertices[i+2]}
+		for j := range tri {
+			tri[j].Position = c.matrix.Project(tri[j].Position)
+		}
+
+		area := edge(tri[0].Position, tri[1].Position, tri[2].Position)
+		if area == 0 {
+			continue
+		}
+		if area < 0 {
+			tri[1], tri[2] = tri[2], tri[1]
+			area = -area
+		}
+
+		minX := math.Max(math.Floor(math.Min(tri[0].Position.X, math.Min(tri[1].Position.X, tri[2].Position.X))), bounds.Min.X)
+		maxX := math.Min(math.Ceil(math.Max(tri[0].Position.X, math.Max(tri[1].Position.X, tri[2].Position.X))), bounds.Max.X)
+		minY := math.Max(math.Floor(math.Min(tri[0].Position.Y, math.Min(tri[1].Position.Y, tri[2].Position.Y))), bounds.Min.Y)
+		maxY := math.Min(math.Ceil(math.Max(tri[0].Position.Y, math.Max(tri[1].Position.Y, tri[2].Position.Y))), bounds.Max.Y)
+
+		for y := minY; y < maxY; y++ {
+			for x := minX; x < maxX; x++ {
+				p := pixel.V(x+0.5, y+0.5)
+
+				weights := [3]float64{
+					edge(tri[1].Position, tri[2].Position, p),
+					edge(tri[2].Position, tri[0].Position, p),
+					edge(tri[0].Position, tri[1].Position, p),
+				}
+
+				if !covers(weights[0], tri[1].Position, tri[2].Position) ||
+					!covers(weights[1], tri[2].Position, tri[0].Position) ||
+					!covers(weights[2], tri[0].Position, tri[1].Position) {
+					continue
+				}
+
+				var vertexColor pixel.RGBA
+				var texCoords pixel.Vec
+				var intensity float64
+				for j := range tri {
+					weight := weights[j] / area
+					vertexColor = vertexColor.Add(tri[j].Color.Scaled(weight))
+					texCoords = texCoords.Add(tri[j].Picture.Scaled(weight))
+					intensity += tri[j].Intensity * weight
+				}
+
+				// This is the same as pixelgl's fragment shader.
+				fragment := vertexColor
+				if picture != nil && intensity != 0 {
+					fragment = vertexColor.Scaled(1 - intensity).Add(vertexColor.Mul(picture.Color(texCoords)).Scaled(intensity))
+				}
+
+				c.blend(int(x), int(y), fragment.Mul(c.mask))
+			}
+		}
+	}
+}
+
+// blend draws a premultiplied color over the pixel at the given pixel coordinates.
+func (c *Canvas) blend(x int, y int, src pixel.RGBA) {
+	if src.A <= 0 {
+		return
+	}
+
+	offset := c.image.PixOffset(x, c.image.Rect.Dy()-1-y)
+	pix := c.image.Pix[offset : offset+4]
+
+	for i, value := range [4]float64{src.R, src.G, src.B, src.A} {
+		blended := value*255 + float64(pix[i])*(1-src.A)
+		pix[i] = uint8(math.Max(0, math.Min(255, math.Round(blended))))
+	}
+}
+
+// edge returns twice the signed area of the triangle a, b, p, which is positive when p is to the left of a->b.
+func edge(a pixel.Vec, b pixel.Vec, p pixel.Vec) float64 {
+	return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+}
+
+// covers returns true if a point with the given edge weight is inside the edge a->b of a counter-clockwise triangle.
+// Points exactly on the edge are only inside if it is a left or top edge.
+func covers(weight float64, a pixel.Vec, b pixel.Vec) bool {
+	if weight != 0 {
+		return weight > 0
+	}
+
+	d := b.Sub(a)
+	return d.Y < 0 || (d.Y == 0 && d.X < 0)
+}
@@ -0,0 +1,38 @@
+package headless
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/systems"
+	"image/color"
+)
+
+// SaveFrameEvent requests that the most recently rendered frame be written to a PNG file at the given path.
+type SaveFrameEvent struct {
+	Path string
+}
+
+// RenderSystem is a system which draws Renderables and HUDLines onto a Canvas on every RenderEvent, in the same way
+// as the window's render system, but without a window, GPU or the main thread. This allows rendering to be tested,
+// e.g. by comparing frames with previously saved images.
+// Frames can be written out by publishing a SaveFrameEvent, or from the canvas directly while the ECS is idle.
+func RenderSystem(e *ecs.ECS, canvas *Canvas) {
+	scene := systems.NewScene()
+
+	events := e.SubscribeTo(append([]interface{}{ecs.RenderEvent{}, SaveFrameEvent{}}, systems.SceneEvents...)...)
+
+	go e.HandleEvents("HeadlessRenderSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.RenderEvent:
+			canvas.Clear(color.RGBA{R: 0, G: 0, B: 0, A: 255})
+			scene.Draw(canvas, event.Alpha)
+
+		case SaveFrameEvent:
+			if err := canvas.SavePNG(event.Path); err != nil {
+				e.ReportError("HeadlessRenderSystem", event, err)
+			}
+
+		default:
+			scene.Track(event)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package headless
+
+import (
+	"flag"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/systems"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata with the frames rendered by the tests")
+
+// goldenTolerance is how far a color channel may stray from the golden image, to allow for floating point differences
+// between platforms.
+const goldenTolerance = 2
+
+// renderFrame sets up a world with the given entities, renders a frame of it and returns the canvas it was drawn on.
+func renderFrame(t *testing.T, setup func(e *ecs.ECS, atlas *systems.Atlas)) *Canvas {
+	t.Helper()
+
+	atlas, err := systems.LoadAtlas("../sprites.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world := ecs.NewECS()
+	e := &world
+	defer e.Close()
+
+	canvas := NewCanvas(160, 120)
+	systems.TransformSystem(e, nil)
+	RenderSystem(e, canvas)
+
+	setup(e, atlas)
+
+	// The entities are only tracked by the end of the first update, so the second one draws them.
+	if err := e.Step(2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	return canvas
+}
+
+// renderable returns the named sprite from the atlas as a Renderable in the given layer.
+func renderable(t *testing.T, atlas *systems.Atlas, name string, layer systems.Layer, zIndex int) *systems.Renderable {
+	t.Helper()
+
+	renderable, err := atlas.Renderable(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderable.Layer, renderable.ZIndex = layer, zIndex
+	return renderable
+}
+
+// compareGolden checks the canvas against testdata/<name>.png, or rewrites that file if the -update flag is set.
+func compareGolden(t *testing.T, canvas *Canvas, name string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".png")
+
+	if *update {
+		if err := canvas.SavePNG(path); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	defer file.Close()
+
+	golden, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := canvas.Image()
+	if got.Bounds() != golden.Bounds() {
+		t.Fatalf("rendered a %v frame, want %v", got.Bounds(), golden.Bounds())
+	}
+
+	var differing int
+	var first image.Point
+	bounds := got.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := golden.At(x, y).RGBA()
+
+			if !near(r1, r2) || !near(g1, g2) || !near(b1, b2) || !near(a1, a2) {
+				if differing == 0 {
+					first = image.Pt(x, y)
+				}
+				differing++
+			}
+		}
+	}
+
+	if differing > 0 {
+		t.Errorf("%d pixels differ from %s, starting at %v", differing, path, first)
+
+		// Keep the frame around for comparison, as the test's temporary directory is removed when it finishes.
+		actual, err := os.CreateTemp("", name+"-*.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer actual.Close()
+
+		if err := canvas.WritePNG(actual); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("the frame was written to %s", actual.Name())
+	}
+}
+
+// near returns whether two 16-bit color channels are within goldenTolerance of each other in 8 bits.
+func near(a, b uint32) bool {
+	return math.Abs(float64(a>>8)-float64(b>>8)) <= goldenTolerance
+}
+
+func TestRenderLayers(t *testing.T) {
+	canvas := renderFrame(t, func(e *ecs.ECS, atlas *systems.Atlas) {
+		// The NPC is drawn over the player despite its ZIndex, as it is in the effects layer.
+		e.AddEntity(&systems.Transform{X: 60, Y: 60}, renderable(t, atlas, "player", systems.LayerWorld, 5))
+		e.AddEntity(&systems.Transform{X: 80, Y: 50}, renderable(t, atlas, "npc", systems.LayerEffects, 0))
+		e.AddEntity(&systems.Transform{X: 90, Y: 70, Rotation: math.Pi / 4},
+			renderable(t, atlas, "bullet", systems.LayerEffects, 1))
+
+		e.AddEntity(&systems.Transform{X: 140, Y: 100}, renderable(t, atlas, "npc", systems.LayerUI, 0))
+		e.AddEntity(&systems.Transform{X: 4, Y: 8}, &systems.HUDLine{Prompt: "Score: 10", FontSize: 1})
+	})
+
+	compareGolden(t, canvas, "layers")
+}
+
+func TestRenderCamera(t *testing.T) {
+	canvas := renderFrame(t, func(e *ecs.ECS, atlas *systems.Atlas) {
+		e.AddEntity(&systems.Camera{X: 500, Y: 300, PrevX: 500, PrevY: 300, Zoom: 2, Rotation: math.Pi / 8,
+			PrevRotation: math.Pi / 8})
+
+		e.AddEntity(&systems.Transform{X: 500, Y: 300}, renderable(t, atlas, "npc", systems.LayerWorld, 0))
+		e.AddEntity(&systems.Transform{X: 530, Y: 310}, renderable(t, atlas, "bullet", systems.LayerWorld, 0))
+		e.AddEntity(&systems.Transform{X: 500, Y: 280},
+			&systems.HUDLine{Prompt: "Alice", Centered: true, FontSize: 1, WorldSpace: true})
+	})
+
+	compareGolden(t, canvas, "camera")
+}
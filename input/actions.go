@@ -0,0 +1,168 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Action is the name of something the player can do, such as "Fire", which may be bound to any number of buttons.
+type Action string
+
+// Bindings maps actions to the buttons which trigger them. Bindings may be changed at any time, including while
+// systems are reading them.
+type Bindings struct {
+	mutex   sync.RWMutex
+	buttons map[Action][]Button
+}
+
+// NewBindings returns a set of bindings with no actions bound.
+func NewBindings() *Bindings {
+	return &Bindings{buttons: make(map[Action][]Button)}
+}
+
+// Bind adds buttons to those which trigger the given action.
+func (b *Bindings) Bind(action Action, buttons ...Button) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, button := range buttons {
+		if !containsButton(b.buttons[action], button) {
+			b.buttons[action] = append(b.buttons[action], button)
+		}
+	}
+}
+
+// Rebind replaces all of the buttons which trigger the given action. With no buttons, the action is unbound.
+func (b *Bindings) Rebind(action Action, buttons ...Button) {
+	b.mutex.Lock()
+	b.buttons[action] = nil
+	b.mutex.Unlock()
+
+	b.Bind(action, buttons...)
+}
+
+// Unbind stops the given button from triggering the given action.
+func (b *Bindings) Unbind(action Action, button Button) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	buttons := b.buttons[action][:0]
+	for _, bound := range b.buttons[action] {
+		if bound != button {
+			buttons = append(buttons, bound)
+		}
+	}
+	b.buttons[action] = buttons
+}
+
+// Buttons returns the buttons which trigger the given action.
+func (b *Bindings) Buttons(action Action) []Button {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return append([]Button(nil), b.buttons[action]...)
+}
+
+// Load reads bindings written by Save, or by hand, from the given reader. The file is a JSON object mapping action
+// names to lists of button names, e.g. `{"Fire": ["KeySpace", "MouseButtonLeft"]}`.
+// Actions listed in the file replace any existing bindings for that action, and all other actions are left as they
+// were, so a file only needs to list the actions it changes.
+func (b *Bindings) Load(r io.Reader) error {
+	var saved map[Action][]string
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return err
+	}
+
+	loaded := make(map[Action][]Button, len(saved))
+	for action, names := range saved {
+		loaded[action] = []Button{}
+
+		for _, name := range names {
+			button, err := ParseButton(name)
+			if err != nil {
+				return fmt.Errorf("binding %s: %w", action, err)
+			}
+			loaded[action] = append(loaded[action], button)
+		}
+	}
+
+	for action, buttons := range loaded {
+		b.Rebind(action, buttons...)
+	}
+
+	return nil
+}
+
+// Save writes all bindings to the given writer in the format read by Load.
+func (b *Bindings) Save(w io.Writer) error {
+	b.mutex.RLock()
+	saved := make(map[Action][]string, len(b.buttons))
+	for action, buttons := range b.buttons {
+		saved[action] = []string{}
+		for _, button := range buttons {
+			saved[action] = append(saved[action], button.String())
+		}
+	}
+	b.mutex.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(saved)
+}
+
+// Actions combines an Input with a set of Bindings, so that systems can check for actions rather than buttons.
+// Since the raw Input is embedded, the mouse position and viewport bounds are still available.
+type Actions struct {
+	Input
+	Bindings *Bindings
+}
+
+// ActionPressed returns true if any of the buttons bound to the action are held down.
+func (a Actions) ActionPressed(action Action) bool {
+	for _, button := range a.Bindings.Buttons(action) {
+		if a.Pressed(button) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionJustPressed returns true if any of the buttons bound to the action were pressed since the last frame.
+func (a Actions) ActionJustPressed(action Action) bool {
+	for _, button := range a.Bindings.Buttons(action) {
+		if a.JustPressed(button) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseButton returns the button with the given name, as returned by Button.String.
+func ParseButton(name string) (Button, error) {
+	button, ok := buttonsByName[name]
+	if !ok {
+		return KeyUnknown, fmt.Errorf("unknown button %q", name)
+	}
+	return button, nil
+}
+
+// buttonsByName is the inverse of buttonNames.
+var buttonsByName = func() map[string]Button {
+	byName := make(map[string]Button, len(buttonNames))
+	for button, name := range buttonNames {
+		byName[name] = button
+	}
+	return byName
+}()
+
+// containsButton returns true if the button is in the list.
+func containsButton(buttons []Button, button Button) bool {
+	for _, b := range buttons {
+		if b == button {
+			return true
+		}
+	}
+	return false
+}
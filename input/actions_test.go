@@ -0,0 +1,83 @@
+package input
+
+import (
+	"bytes"
+	"github.com/faiface/pixel"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkButtons fails the test if the action isn't bound to exactly the given buttons, in order.
+func checkButtons(t *testing.T, bindings *Bindings, action Action, want ...Button) {
+	t.Helper()
+
+	if got := bindings.Buttons(action); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s is bound to %v, want %v", action, got, want)
+	}
+}
+
+func TestBindingsRoundTrip(t *testing.T) {
+	bindings := NewBindings()
+	bindings.Bind("Fire", KeySpace, MouseButtonLeft)
+	bindings.Bind("Up", KeyW)
+	bindings.Bind("Jump", KeyA)
+	bindings.Unbind("Jump", KeyA)
+
+	var saved bytes.Buffer
+	if err := bindings.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewBindings()
+	if err := loaded.Load(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	checkButtons(t, loaded, "Fire", KeySpace, MouseButtonLeft)
+	checkButtons(t, loaded, "Up", KeyW)
+	checkButtons(t, loaded, "Jump")
+}
+
+func TestBindingsRebind(t *testing.T) {
+	bindings := NewBindings()
+	bindings.Bind("Fire", KeySpace)
+	memory := NewMemory(pixel.R(0, 0, 100, 100))
+	actions := Actions{Input: memory, Bindings: bindings}
+
+	bindings.Rebind("Fire", KeyW, MouseButtonLeft)
+	checkButtons(t, bindings, "Fire", KeyW, MouseButtonLeft)
+
+	memory.Press(KeySpace)
+	if actions.ActionPressed("Fire") {
+		t.Error("Fire is still triggered by its old button")
+	}
+	memory.Press(KeyW)
+	if !actions.ActionPressed("Fire") {
+		t.Error("Fire isn't triggered by its new button")
+	}
+
+	bindings.Rebind("Fire")
+	checkButtons(t, bindings, "Fire")
+}
+
+func TestBindingsLoad(t *testing.T) {
+	bindings := NewBindings()
+	bindings.Bind("Fire", KeySpace)
+	bindings.Bind("Up", KeyW)
+
+	// Only the actions in the file are changed.
+	if err := bindings.Load(strings.NewReader(`{"Fire": ["MouseButtonLeft"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	checkButtons(t, bindings, "Fire", MouseButtonLeft)
+	checkButtons(t, bindings, "Up", KeyW)
+
+	// A file with an unknown button is rejected without changing anything.
+	err := bindings.Load(strings.NewReader(`{"Up": ["KeyA"], "Fire": ["KeySpace", "KeyFlux"]}`))
+	if err == nil || !strings.Contains(err.Error(), "KeyFlux") {
+		t.Errorf("got %v, want an error naming the unknown button", err)
+	}
+	checkButtons(t, bindings, "Fire", MouseButtonLeft)
+	checkButtons(t, bindings, "Up", KeyW)
+}
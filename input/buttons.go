@@ -0,0 +1,288 @@
+package input
+
+// Button is a keyboard key or mouse button. The values are the same as those of pixelgl.Button, so that converting
+// between the two is a simple cast.
+type Button int
+
+// List of all mouse buttons.
+const (
+	MouseButton1      Button = 0
+	MouseButton2      Button = 1
+	MouseButton3      Button = 2
+	MouseButton4      Button = 3
+	MouseButton5      Button = 4
+	MouseButton6      Button = 5
+	MouseButton7      Button = 6
+	MouseButton8      Button = 7
+	MouseButtonLast          = MouseButton8
+	MouseButtonLeft          = MouseButton1
+	MouseButtonRight         = MouseButton2
+	MouseButtonMiddle        = MouseButton3
+)
+
+// List of all keyboard keys.
+const (
+	KeyUnknown      Button = -1
+	KeySpace        Button = 32
+	KeyApostrophe   Button = 39
+	KeyComma        Button = 44
+	KeyMinus        Button = 45
+	KeyPeriod       Button = 46
+	KeySlash        Button = 47
+	Key0            Button = 48
+	Key1            Button = 49
+	Key2            Button = 50
+	Key3            Button = 51
+	Key4            Button = 52
+	Key5            Button = 53
+	Key6            Button = 54
+	Key7            Button = 55
+	Key8            Button = 56
+	Key9            Button = 57
+	KeySemicolon    Button = 59
+	KeyEqual        Button = 61
+	KeyA            Button = 65
+	KeyB            Button = 66
+	KeyC            Button = 67
+	KeyD            Button = 68
+	KeyE            Button = 69
+	KeyF            Button = 70
+	KeyG            Button = 71
+	KeyH            Button = 72
+	KeyI            Button = 73
+	KeyJ            Button = 74
+	KeyK            Button = 75
+	KeyL            Button = 76
+	KeyM            Button = 77
+	KeyN            Button = 78
+	KeyO            Button = 79
+	KeyP            Button = 80
+	KeyQ            Button = 81
+	KeyR            Button = 82
+	KeyS            Button = 83
+	KeyT            Button = 84
+	KeyU            Button = 85
+	KeyV            Button = 86
+	KeyW            Button = 87
+	KeyX            Button = 88
+	KeyY            Button = 89
+	KeyZ            Button = 90
+	KeyLeftBracket  Button = 91
+	KeyBackslash    Button = 92
+	KeyRightBracket Button = 93
+	KeyGraveAccent  Button = 96
+	KeyWorld1       Button = 161
+	KeyWorld2       Button = 162
+	KeyEscape       Button = 256
+	KeyEnter        Button = 257
+	KeyTab          Button = 258
+	KeyBackspace    Button = 259
+	KeyInsert       Button = 260
+	KeyDelete       Button = 261
+	KeyRight        Button = 262
+	KeyLeft         Button = 263
+	KeyDown         Button = 264
+	KeyUp           Button = 265
+	KeyPageUp       Button = 266
+	KeyPageDown     Button = 267
+	KeyHome         Button = 268
+	KeyEnd          Button = 269
+	KeyCapsLock     Button = 280
+	KeyScrollLock   Button = 281
+	KeyNumLock      Button = 282
+	KeyPrintScreen  Button = 283
+	KeyPause        Button = 284
+	KeyF1           Button = 290
+	KeyF2           Button = 291
+	KeyF3           Button = 292
+	KeyF4           Button = 293
+	KeyF5           Button = 294
+	KeyF6           Button = 295
+	KeyF7           Button = 296
+	KeyF8           Button = 297
+	KeyF9           Button = 298
+	KeyF10          Button = 299
+	KeyF11          Button = 300
+	KeyF12          Button = 301
+	KeyF13          Button = 302
+	KeyF14          Button = 303
+	KeyF15          Button = 304
+	KeyF16          Button = 305
+	KeyF17          Button = 306
+	KeyF18          Button = 307
+	KeyF19          Button = 308
+	KeyF20          Button = 309
+	KeyF21          Button = 310
+	KeyF22          Button = 311
+	KeyF23          Button = 312
+	KeyF24          Button = 313
+	KeyF25          Button = 314
+	KeyKP0          Button = 320
+	KeyKP1          Button = 321
+	KeyKP2          Button = 322
+	KeyKP3          Button = 323
+	KeyKP4          Button = 324
+	KeyKP5          Button = 325
+	KeyKP6          Button = 326
+	KeyKP7          Button = 327
+	KeyKP8          Button = 328
+	KeyKP9          Button = 329
+	KeyKPDecimal    Button = 330
+	KeyKPDivide     Button = 331
+	KeyKPMultiply   Button = 332
+	KeyKPSubtract   Button = 333
+	KeyKPAdd        Button = 334
+	KeyKPEnter      Button = 335
+	KeyKPEqual      Button = 336
+	KeyLeftShift    Button = 340
+	KeyLeftControl  Button = 341
+	KeyLeftAlt      Button = 342
+	KeyLeftSuper    Button = 343
+	KeyRightShift   Button = 344
+	KeyRightControl Button = 345
+	KeyRightAlt     Button = 346
+	KeyRightSuper   Button = 347
+	KeyMenu         Button = 348
+	KeyLast                = KeyMenu
+)
+
+// String returns a human-readable name for the button, e.g. "KeySpace".
+func (b Button) String() string {
+	name, ok := buttonNames[b]
+	if !ok {
+		return "Invalid"
+	}
+	return name
+}
+
+// buttonNames maps each button to the name used by String and ParseButton.
+var buttonNames = map[Button]string{
+	MouseButton4:      "MouseButton4",
+	MouseButton5:      "MouseButton5",
+	MouseButton6:      "MouseButton6",
+	MouseButton7:      "MouseButton7",
+	MouseButton8:      "MouseButton8",
+	MouseButtonLeft:   "MouseButtonLeft",
+	MouseButtonRight:  "MouseButtonRight",
+	MouseButtonMiddle: "MouseButtonMiddle",
+	KeySpace:          "KeySpace",
+	KeyApostrophe:     "KeyApostrophe",
+	KeyComma:          "KeyComma",
+	KeyMinus:          "KeyMinus",
+	KeyPeriod:         "KeyPeriod",
+	KeySlash:          "KeySlash",
+	Key0:              "Key0",
+	Key1:              "Key1",
+	Key2:              "Key2",
+	Key3:              "Key3",
+	Key4:              "Key4",
+	Key5:              "Key5",
+	Key6:              "Key6",
+	Key7:              "Key7",
+	Key8:              "Key8",
+	Key9:              "Key9",
+	KeySemicolon:      "KeySemicolon",
+	KeyEqual:          "KeyEqual",
+	KeyA:              "KeyA",
+	KeyB:              "KeyB",
+	KeyC:              "KeyC",
+	KeyD:              "KeyD",
+	KeyE:              "KeyE",
+	KeyF:              "KeyF",
+	KeyG:              "KeyG",
+	KeyH:              "KeyH",
+	KeyI:              "KeyI",
+	KeyJ:              "KeyJ",
+	KeyK:              "KeyK",
+	KeyL:              "KeyL",
+	KeyM:              "KeyM",
+	KeyN:              "KeyN",
+	KeyO:              "KeyO",
+	KeyP:              "KeyP",
+	KeyQ:              "KeyQ",
+	KeyR:              "KeyR",
+	KeyS:              "KeyS",
+	KeyT:              "KeyT",
+	KeyU:              "KeyU",
+	KeyV:              "KeyV",
+	KeyW:              "KeyW",
+	KeyX:              "KeyX",
+	KeyY:              "KeyY",
+	KeyZ:              "KeyZ",
+	KeyLeftBracket:    "KeyLeftBracket",
+	KeyBackslash:      "KeyBackslash",
+	KeyRightBracket:   "KeyRightBracket",
+	KeyGraveAccent:    "KeyGraveAccent",
+	KeyWorld1:         "KeyWorld1",
+	KeyWorld2:         "KeyWorld2",
+	KeyEscape:         "KeyEscape",
+	KeyEnter:          "KeyEnter",
+	KeyTab:            "KeyTab",
+	KeyBackspace:      "KeyBackspace",
+	KeyInsert:         "KeyInsert",
+	KeyDelete:         "KeyDelete",
+	KeyRight:          "KeyRight",
+	KeyLeft:           "KeyLeft",
+	KeyDown:           "KeyDown",
+	KeyUp:             "KeyUp",
+	KeyPageUp:         "KeyPageUp",
+	KeyPageDown:       "KeyPageDown",
+	KeyHome:           "KeyHome",
+	KeyEnd:            "KeyEnd",
+	KeyCapsLock:       "KeyCapsLock",
+	KeyScrollLock:     "KeyScrollLock",
+	KeyNumLock:        "KeyNumLock",
+	KeyPrintScreen:    "KeyPrintScreen",
+	KeyPause:          "KeyPause",
+	KeyF1:             "KeyF1",
+	KeyF2:             "KeyF2",
+	KeyF3:             "KeyF3",
+	KeyF4:             "KeyF4",
+	KeyF5:             "KeyF5",
+	KeyF6:             "KeyF6",
+	KeyF7:             "KeyF7",
+	KeyF8:             "KeyF8",
+	KeyF9:             "KeyF9",
+	KeyF10:            "KeyF10",
+	KeyF11:            "KeyF11",
+	KeyF12:            "KeyF12",
+	KeyF13:            "KeyF13",
+	KeyF14:            "KeyF14",
+	KeyF15:            "KeyF15",
+	KeyF16:            "KeyF16",
+	KeyF17:            "KeyF17",
+	KeyF18:            "KeyF18",
+	KeyF19:            "KeyF19",
+	KeyF20:            "KeyF20",
+	KeyF21:            "KeyF21",
+	KeyF22:            "KeyF22",
+	KeyF23:            "KeyF23",
+	KeyF24:            "KeyF24",
+	KeyF25:            "KeyF25",
+	KeyKP0:            "KeyKP0",
+	KeyKP1:            "KeyKP1",
+	KeyKP2:            "KeyKP2",
+	KeyKP3:            "KeyKP3",
+	KeyKP4:            "KeyKP4",
+	KeyKP5:            "KeyKP5",
+	KeyKP6:            "KeyKP6",
+	KeyKP7:            "KeyKP7",
+	KeyKP8:            "KeyKP8",
+	KeyKP9:            "KeyKP9",
+	KeyKPDecimal:      "KeyKPDecimal",
+	KeyKPDivide:       "KeyKPDivide",
+	KeyKPMultiply:     "KeyKPMultiply",
+	KeyKPSubtract:     "KeyKPSubtract",
+	KeyKPAdd:          "KeyKPAdd",
+	KeyKPEnter:        "KeyKPEnter",
+	KeyKPEqual:        "KeyKPEqual",
+	KeyLeftShift:      "KeyLeftShift",
+	KeyLeftControl:    "KeyLeftControl",
+	KeyLeftAlt:        "KeyLeftAlt",
+	KeyLeftSuper:      "KeyLeftSuper",
+	KeyRightShift:     "KeyRightShift",
+	KeyRightControl:   "KeyRightControl",
+	KeyRightAlt:       "KeyRightAlt",
+	KeyRightSuper:     "KeyRightSuper",
+	KeyMenu:           "KeyMenu",
+}
@@ -0,0 +1,81 @@
+package input
+
+import "github.com/faiface/pixel"
+
+// Input is the source of player input used by systems.
+// The window package adapts a pixelgl window to it, and Memory provides input from code, for tests and headless use.
+type Input interface {
+	Pressed(button Button) bool     // Whether the button is currently held down.
+	JustPressed(button Button) bool // Whether the button was pressed since the last frame.
+	MousePosition() pixel.Vec       // The position of the mouse within the viewport.
+	Bounds() pixel.Rect             // The bounds of the viewport.
+}
+
+// Memory is an Input whose state is set from code, e.g. by tests, bots, or a server receiving input over the network.
+type Memory struct {
+	pressed     map[Button]bool
+	justPressed map[Button]bool
+	mouse       pixel.Vec
+	bounds      pixel.Rect
+}
+
+// NewMemory returns a Memory with nothing pressed and the given viewport bounds.
+func NewMemory(bounds pixel.Rect) *Memory {
+	return &Memory{
+		pressed:     make(map[Button]bool),
+		justPressed: make(map[Button]bool),
+		bounds:      bounds,
+	}
+}
+
+// Press holds the given buttons down. Buttons which weren't already held are also just pressed until EndFrame.
+func (m *Memory) Press(buttons ...Button) {
+	for _, button := range buttons {
+		if !m.pressed[button] {
+			m.justPressed[button] = true
+		}
+		m.pressed[button] = true
+	}
+}
+
+// Release lets go of the given buttons.
+func (m *Memory) Release(buttons ...Button) {
+	for _, button := range buttons {
+		delete(m.pressed, button)
+	}
+}
+
+// MoveMouse moves the mouse to the given position.
+func (m *Memory) MoveMouse(position pixel.Vec) {
+	m.mouse = position
+}
+
+// SetBounds resizes the viewport.
+func (m *Memory) SetBounds(bounds pixel.Rect) {
+	m.bounds = bounds
+}
+
+// EndFrame should be called after each frame, so that buttons are only just pressed for a single frame.
+func (m *Memory) EndFrame() {
+	m.justPressed = make(map[Button]bool)
+}
+
+// Pressed returns true if the button is held down.
+func (m *Memory) Pressed(button Button) bool {
+	return m.pressed[button]
+}
+
+// JustPressed returns true if the button was pressed during the current frame.
+func (m *Memory) JustPressed(button Button) bool {
+	return m.justPressed[button]
+}
+
+// MousePosition returns the position of the mouse.
+func (m *Memory) MousePosition() pixel.Vec {
+	return m.mouse
+}
+
+// Bounds returns the bounds of the viewport.
+func (m *Memory) Bounds() pixel.Rect {
+	return m.bounds
+}
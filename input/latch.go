@@ -0,0 +1,46 @@
+package input
+
+import "github.com/emctague/go-loopy/ecs"
+
+// Latch is an Input which holds on to buttons that were just pressed until the ECS runs its next update, so that each
+// press is just pressed in exactly one update. With a fixed timestep, a frame may run several updates or none at all,
+// and the input it wraps would report the press in every one of them, or in none if the frame ran no updates.
+// The latch checks for presses whenever it ticks, so it should be both the ECS's Clock and the Input passed to systems,
+// wrapping the clock and input which were used before, e.g.:
+//
+//	latch := input.NewLatch(&e, in, e.Clock)
+//	e.Clock, in = latch, latch
+type Latch struct {
+	Input
+	Clock ecs.Clock
+
+	e       *ecs.ECS
+	latched map[Button]uint64 // The update in which each button is just pressed.
+}
+
+// NewLatch returns a Latch which holds on to presses from the given input until the ECS has run an update.
+func NewLatch(e *ecs.ECS, input Input, clock ecs.Clock) *Latch {
+	return &Latch{Input: input, Clock: clock, e: e, latched: make(map[Button]uint64)}
+}
+
+// Tick ticks the wrapped clock, and latches any buttons just pressed since the last tick until the next update.
+func (l *Latch) Tick() float64 {
+	delta := l.Clock.Tick()
+	next := l.e.Updates() + 1
+
+	for button := MouseButton1; button <= KeyLast; button++ {
+		if l.Input.JustPressed(button) {
+			l.latched[button] = next
+		} else if l.latched[button] < next {
+			delete(l.latched, button)
+		}
+	}
+
+	return delta
+}
+
+// JustPressed returns true if the button was pressed before the current update, but after the one before it.
+func (l *Latch) JustPressed(button Button) bool {
+	update, ok := l.latched[button]
+	return ok && update == l.e.Updates()
+}
@@ -0,0 +1,71 @@
+package input
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"reflect"
+	"testing"
+)
+
+// latchFrame is a frame of a scripted game, which may press the space bar before lasting for the given time.
+type latchFrame struct {
+	press bool
+	delta float64
+}
+
+// scriptedClock plays through a list of frames, ending each of them and pressing buttons before the next, and stops
+// the ECS once they have all been played.
+type scriptedClock struct {
+	e      *ecs.ECS
+	memory *Memory
+	frames []latchFrame
+}
+
+func (c *scriptedClock) Tick() float64 {
+	c.memory.EndFrame()
+	c.memory.Release(KeySpace)
+
+	if len(c.frames) == 0 {
+		c.e.Stop()
+		return 0
+	}
+
+	frame := c.frames[0]
+	c.frames = c.frames[1:]
+	if frame.press {
+		c.memory.Press(KeySpace)
+	}
+
+	return frame.delta
+}
+
+func TestLatchJustPressedOncePerUpdate(t *testing.T) {
+	e := ecs.NewECS()
+	e.FixedStep = 0.25
+	e.MaxFrameTime = 1
+
+	memory := NewMemory(pixel.R(0, 0, 100, 100))
+	latch := NewLatch(&e, memory, &scriptedClock{&e, memory, []latchFrame{
+		{true, 0.75},   // Three updates, only the first of which sees the press.
+		{false, 0.25},  // One update, after the press has been seen.
+		{true, 0.125},  // No updates, so the press is held on to...
+		{false, 0.125}, // ...until this frame's update.
+		{false, 0.25},
+	}})
+	e.Clock = latch
+
+	var seen []uint64
+	go e.HandleEvents("PressingSystem", e.SubscribeTo(ecs.UpdateBeginEvent{}), func(ev ecs.EventContainer) {
+		if latch.JustPressed(KeySpace) {
+			seen = append(seen, e.Updates())
+		}
+	})
+
+	if err := e.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []uint64{1, 5}; !reflect.DeepEqual(seen, want) || e.Updates() != 6 {
+		t.Errorf("space was just pressed in updates %v of %d, want %v of 6", seen, e.Updates(), want)
+	}
+}
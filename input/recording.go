@@ -0,0 +1,145 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"io"
+)
+
+// Frame is the state of all input during a single frame, along with the frame's delta.
+type Frame struct {
+	Delta       float64
+	Pressed     []Button
+	JustPressed []Button
+	Mouse       pixel.Vec
+	Bounds      pixel.Rect
+}
+
+// Recorder is a Clock which writes the state of an Input to a file every frame, along with the delta reported by
+// the Clock it wraps. The recording can be played back with a Playback.
+type Recorder struct {
+	Input Input
+	Clock ecs.Clock
+	Err   error // The first error encountered while writing, after which recording stops.
+
+	encoder *json.Encoder
+}
+
+// NewRecorder returns a Recorder which records the given input and clock to the given writer.
+// It should be used as the ECS's Clock, while systems continue to use the original Input.
+func NewRecorder(input Input, clock ecs.Clock, w io.Writer) *Recorder {
+	return &Recorder{Input: input, Clock: clock, encoder: json.NewEncoder(w)}
+}
+
+// Tick ticks the wrapped clock and records the current input state.
+func (r *Recorder) Tick() float64 {
+	frame := Frame{
+		Delta:  r.Clock.Tick(),
+		Mouse:  r.Input.MousePosition(),
+		Bounds: r.Input.Bounds(),
+	}
+
+	for button := MouseButton1; button <= KeyLast; button++ {
+		if r.Input.Pressed(button) {
+			frame.Pressed = append(frame.Pressed, button)
+		}
+		if r.Input.JustPressed(button) {
+			frame.JustPressed = append(frame.JustPressed, button)
+		}
+	}
+
+	if r.Err == nil {
+		r.Err = r.encoder.Encode(frame)
+	}
+
+	return frame.Delta
+}
+
+// Playback plays back a recording made by a Recorder. It is both the Input to be passed to systems and the
+// Clock to be used by the ECS, so that the systems see exactly the same input and deltas as during the recording.
+type Playback struct {
+	Frames     []Frame
+	OnFinished func() // Called when Tick is called after the last frame, e.g. to stop the ECS.
+
+	current     int
+	pressed     map[Button]bool
+	justPressed map[Button]bool
+}
+
+// LoadPlayback reads a recording made by a Recorder.
+func LoadPlayback(r io.Reader) (*Playback, error) {
+	playback := &Playback{current: -1}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var frame Frame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, err
+		}
+		playback.Frames = append(playback.Frames, frame)
+	}
+
+	return playback, scanner.Err()
+}
+
+// Tick moves on to the next recorded frame and returns its delta.
+// Once the recording is finished, all input is released, zero is returned, and OnFinished is called.
+func (p *Playback) Tick() float64 {
+	p.current++
+	p.pressed = make(map[Button]bool)
+	p.justPressed = make(map[Button]bool)
+
+	frame, ok := p.frame()
+	if !ok {
+		if p.OnFinished != nil {
+			p.OnFinished()
+		}
+		return 0
+	}
+
+	for _, button := range frame.Pressed {
+		p.pressed[button] = true
+	}
+	for _, button := range frame.JustPressed {
+		p.justPressed[button] = true
+	}
+
+	return frame.Delta
+}
+
+// Finished returns true once every recorded frame has been played.
+func (p *Playback) Finished() bool {
+	return p.current >= len(p.Frames)
+}
+
+// frame returns the current frame, if there is one.
+func (p *Playback) frame() (Frame, bool) {
+	if p.current < 0 || p.current >= len(p.Frames) {
+		return Frame{}, false
+	}
+	return p.Frames[p.current], true
+}
+
+// Pressed returns true if the button was held during the current frame.
+func (p *Playback) Pressed(button Button) bool {
+	return p.pressed[button]
+}
+
+// JustPressed returns true if the button was pressed at the start of the current frame.
+func (p *Playback) JustPressed(button Button) bool {
+	return p.justPressed[button]
+}
+
+// MousePosition returns the recorded mouse position for the current frame.
+func (p *Playback) MousePosition() pixel.Vec {
+	frame, _ := p.frame()
+	return frame.Mouse
+}
+
+// Bounds returns the recorded window bounds for the current frame.
+func (p *Playback) Bounds() pixel.Rect {
+	frame, _ := p.frame()
+	return frame.Bounds
+}
@@ -1,22 +1,38 @@
 package main
 
 import (
+	"flag"
 	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
 	"github.com/emctague/go-loopy/systems"
 	"github.com/emctague/go-loopy/utils"
+	"github.com/emctague/go-loopy/window"
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
 	"log"
+	"os"
 	"strconv"
 )
 
 func main() {
+	record := flag.String("record", "", "record all input to the given file")
+	controls := flag.String("controls", "", "load key bindings from the given file, e.g.: {\"Fire\": [\"KeySpace\"]}")
+	replay := flag.String("replay", "", "play back input recorded to the given file, instead of using the keyboard and mouse")
+	flag.Parse()
+
 	pixelgl.Run(func() {
-		pic, err := systems.LoadPicture("./sprites.png")
+		atlas, err := systems.LoadAtlas("./sprites.json")
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		// Sprites are all looked up before starting, so that any missing from the atlas are reported straight away.
+		playerSprite := mustRenderable(atlas, "player")
+		aliceSprite := mustRenderable(atlas, "npc")
+		rodSprite := mustRenderable(atlas, "npc")
+		bulletSprite := mustRenderable(atlas, "bullet")
+		bulletSprite.Layer = systems.LayerEffects
+
 		cfg := pixelgl.WindowConfig{Title: "PixelGL!!!", Bounds: pixel.R(0, 0, 1024, 768), VSync: true}
 
 		win, err := pixelgl.NewWindow(cfg)
@@ -25,20 +41,80 @@ func main() {
 		}
 
 		e := ecs.NewECS()
+		e.FixedStep = 1.0 / 60
+
+		// Input normally comes from the window, but may be recorded or come from a recording instead.
+		var in input.Input = window.Input{Window: win}
+
+		if *record != "" {
+			file, err := os.Create(*record)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer file.Close()
+
+			e.Clock = input.NewRecorder(in, e.Clock, file)
+		}
+
+		if *replay != "" {
+			file, err := os.Open(*replay)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			playback, err := input.LoadPlayback(file)
+			file.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			playback.OnFinished = e.Stop
+			in = playback
+			e.Clock = playback
+		}
+
+		// A frame may run several fixed updates or none, so presses are latched until the next update sees them.
+		latch := input.NewLatch(&e, in, e.Clock)
+		in, e.Clock = latch, latch
+
+		// Systems which respond to the player's controls check for actions, which are bound to buttons.
+		bindings := systems.DefaultBindings()
+
+		if *controls != "" {
+			file, err := os.Open(*controls)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = bindings.Load(file)
+			file.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		actions := input.Actions{Input: in, Bindings: bindings}
+
+		// The spatial index lets systems find entities near each other without checking every entity.
+		index := systems.NewSpatialIndex(64)
 
 		// Add all systems
-		systems.TransformSystem(&e)
-		systems.PhysicsSystem(&e, win)
-		systems.PlayerSystem(&e, win, &pic)
+		systems.TransformSystem(&e, index)
+		systems.CameraSystem(&e)
+		systems.AnimationSystem(&e)
+		systems.PhysicsSystem(&e, pixel.ZV)
+		systems.PlayerSystem(&e, actions, *bulletSprite)
 		systems.ParticleSystem(&e)
 		systems.BalanceSystem(&e)
-		systems.InteractiveSystem(&e, win)
-		systems.DigSystem(&e, win)
-		systems.ProjectileSystem(&e, win)
-		systems.BulletSystem(&e)
+		systems.HUDSystem(&e)
+		systems.InteractiveSystem(&e, actions, index)
+		systems.DigSystem(&e, actions, index)
+		systems.ProjectileSystem(&e, in, index)
+		systems.BulletSystem(&e, index)
+		systems.CollisionSystem(&e, index)
 
 		// The render system needs to run on the main thread, so we let it transfer our setup to a goroutine.
-		systems.RenderSystem(&e, win, func() {
+		window.RenderSystem(&e, win, func() {
 
 			// Create player entity. The wallet is stored separately so that it can be interacted with from the NPC
 			// scripts provided below.
@@ -47,7 +123,12 @@ func main() {
 				pWallet,
 				&systems.Physics{DragFactor: 0.93},
 				&systems.Player{}, &systems.Interactor{},
-				&systems.Renderable{Sprite: pixel.NewSprite(pic, pixel.R(2, 3, 2+64, 3+64))})
+				&systems.Collider{Shape: systems.ShapeCircle, Radius: 24, Layer: systems.CollisionLayerPlayer},
+				playerSprite)
+
+			// The camera starts out showing the same area as the window, and follows the player once they get close to
+			// its edges.
+			e.AddEntity(&systems.Camera{X: 512, Y: 384, Target: player, DeadZoneWidth: 600, DeadZoneHeight: 400, Smoothing: 0.2})
 
 			e.AddEntity(&systems.Transform{X: 200, Y: 200, Width: 27, Height: 27}, &systems.Interactive{
 				Prompt: "[space] Talk", Name: "Alice",
@@ -65,9 +146,11 @@ func main() {
 						}
 					}
 				}),
-			}, &systems.Enemy{Health: 10}, &systems.Renderable{Sprite: pixel.NewSprite(pic, pixel.R(69, 40, 69+27, 40+27))}, &systems.Diggable{1, 1})
+			}, &systems.Enemy{Health: 10}, aliceSprite, &systems.Diggable{BaseDurability: 1, Durability: 1},
+				&systems.Collider{Layer: systems.CollisionLayerEnemy})
 
-			e.AddEntity(&systems.Transform{X: 500, Y: 300, Width: 27, Height: 27}, &systems.Enemy{Health: 10}, &systems.Renderable{Sprite: pixel.NewSprite(pic, pixel.R(69, 40, 69+27, 40+27))},
+			e.AddEntity(&systems.Transform{X: 500, Y: 300, Width: 27, Height: 27}, &systems.Enemy{Health: 10}, rodSprite,
+				&systems.Collider{Layer: systems.CollisionLayerEnemy},
 				&systems.Interactive{
 					Prompt: "[space] talk", Name: "Rod",
 					Menu: utils.MakeDialogScript(func(prompt utils.PromptTool, ev **ecs.EventContainer) {
@@ -92,7 +175,19 @@ func main() {
 					}),
 				})
 
-			e.Run()
+			if err := e.Run(); err != nil {
+				log.Fatal(err)
+			}
 		})
 	})
 }
+
+// mustRenderable returns a new Renderable for the named sprite in the atlas, exiting if there is no such sprite.
+func mustRenderable(atlas *systems.Atlas, name string) *systems.Renderable {
+	renderable, err := atlas.Renderable(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return renderable
+}
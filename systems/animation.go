@@ -0,0 +1,196 @@
+package systems
+
+import (
+	"fmt"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+)
+
+// AnimationMode determines what a clip does once it reaches its last frame.
+type AnimationMode string
+
+const (
+	AnimationLoop     AnimationMode = "loop"     // Start again from the first frame.
+	AnimationOnce     AnimationMode = "once"     // Stay on the last frame, and publish an AnimationFinishedEvent.
+	AnimationPingPong AnimationMode = "pingpong" // Play the frames backwards, then forwards again, and so on.
+)
+
+// ClipFrame is a single frame of an animation clip.
+type ClipFrame struct {
+	Frame    pixel.Rect // The area of the sprite's picture to show.
+	Duration float64    // How long the frame is shown for, in seconds.
+}
+
+// Clip is a named sequence of frames which can be played by an Animation.
+type Clip struct {
+	Frames []ClipFrame
+	Mode   AnimationMode
+}
+
+// Animation is a component which animates an entity's Renderable by showing each frame of a clip in turn.
+type Animation struct {
+	Clips   map[string]*Clip // The clips which may be played, by name. These are usually shared with an Atlas.
+	Playing string           // The name of the clip currently playing.
+
+	Frame    int     // The index of the frame currently shown.
+	Elapsed  float64 // How long the current frame has been shown for.
+	Reverse  bool    // Whether a ping-pong clip is currently playing backwards.
+	Finished bool    // Whether a clip played once has reached its end.
+}
+
+// PlayAnimationEvent starts playing a clip from its first frame. If the clip is already playing and hasn't finished,
+// it carries on uninterrupted unless Restart is set.
+type PlayAnimationEvent struct {
+	ID      ecs.EntityID
+	Clip    string
+	Restart bool
+}
+
+// TargetEntity returns the entity whose animation is changing.
+func (p PlayAnimationEvent) TargetEntity() ecs.EntityID {
+	return p.ID
+}
+
+// AnimationFinishedEvent is published when a clip which plays once reaches its end.
+type AnimationFinishedEvent struct {
+	ID   ecs.EntityID
+	Clip string
+}
+
+// TargetEntity returns the entity whose animation finished.
+func (a AnimationFinishedEvent) TargetEntity() ecs.EntityID {
+	return a.ID
+}
+
+// clip returns the clip currently playing, if it has any frames.
+func (a *Animation) clip() (*Clip, bool) {
+	clip, ok := a.Clips[a.Playing]
+	return clip, ok && len(clip.Frames) > 0
+}
+
+// advance moves the animation forward by the given number of seconds, returning true if a clip played once has just
+// finished.
+func (a *Animation) advance(delta float64) bool {
+	clip, ok := a.clip()
+	if !ok || a.Finished {
+		return false
+	}
+
+	last := len(clip.Frames) - 1
+	a.Frame = clampFrame(a.Frame, last)
+	a.Elapsed += delta
+
+	for {
+		duration := clip.Frames[a.Frame].Duration
+		if duration <= 0 || a.Elapsed < duration {
+			return false
+		}
+
+		a.Elapsed -= duration
+
+		switch {
+		case clip.Mode == AnimationOnce && a.Frame == last:
+			a.Elapsed = 0
+			a.Finished = true
+			return true
+
+		case clip.Mode == AnimationPingPong && last > 0:
+			if a.Frame == last {
+				a.Reverse = true
+			} else if a.Frame == 0 {
+				a.Reverse = false
+			}
+
+			if a.Reverse {
+				a.Frame--
+			} else {
+				a.Frame++
+			}
+
+		default:
+			a.Frame = (a.Frame + 1) % (last + 1)
+		}
+	}
+}
+
+// apply shows the animation's current frame on the given renderable.
+func (a *Animation) apply(renderable *Renderable) {
+	clip, ok := a.clip()
+	if !ok || renderable.Sprite == nil {
+		return
+	}
+
+	renderable.Sprite.Set(renderable.Sprite.Picture(), clip.Frames[clampFrame(a.Frame, len(clip.Frames)-1)].Frame)
+}
+
+// clampFrame keeps a frame index within the range of a clip.
+func clampFrame(frame int, last int) int {
+	if frame < 0 {
+		return 0
+	}
+	if frame > last {
+		return last
+	}
+	return frame
+}
+
+type eAnimated struct {
+	*Animation
+	*Renderable
+}
+
+// AnimationSystem plays the animations of entities with both an Animation and a Renderable, advancing them at the
+// start of every update.
+func AnimationSystem(e *ecs.ECS) {
+	entities := make(map[ecs.EntityID]eAnimated)
+
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ecs.UpdateBeginEvent{}, PlayAnimationEvent{})
+
+	go e.HandleEvents("AnimationSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			if entity := ecs.RefreshEntityOf(event, entities); entity != nil {
+				entity.apply(entity.Renderable)
+			}
+
+		case ecs.EntityRemovedEvent:
+			delete(entities, event.ID)
+
+		case PlayAnimationEvent:
+			entity, ok := entities[event.ID]
+			if !ok {
+				e.ReportError("AnimationSystem", event, fmt.Errorf("cannot play %q on an entity without an animation", event.Clip))
+				break
+			}
+
+			if _, ok := entity.Clips[event.Clip]; !ok {
+				e.ReportError("AnimationSystem", event, fmt.Errorf("animation has no clip named %q", event.Clip))
+				break
+			}
+
+			if entity.Playing == event.Clip && !entity.Finished && !event.Restart {
+				break
+			}
+
+			entity.Playing, entity.Frame, entity.Elapsed, entity.Reverse, entity.Finished = event.Clip, 0, 0, false, false
+			entity.apply(entity.Renderable)
+
+		case ecs.UpdateBeginEvent:
+			// Many clips may finish at once, so the finishes are published together, in order of ID.
+			var finished ecs.EventBatch
+			for _, id := range sortedIDs(entities) {
+				entity := entities[id]
+				if entity.advance(event.Delta) {
+					finished = append(finished, AnimationFinishedEvent{id, entity.Playing})
+				}
+
+				entity.apply(entity.Renderable)
+			}
+
+			if len(finished) > 0 {
+				ev.Next <- finished
+			}
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"testing"
+)
+
+// loadAnimatedAtlas loads the test atlas, whose clips cover every animation mode.
+func loadAnimatedAtlas(t *testing.T) *Atlas {
+	t.Helper()
+
+	atlas, err := LoadAtlas("testdata/animated.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return atlas
+}
+
+// playFrames steps the ECS once for each of the given sprites, checking that the renderable shows each in turn.
+func playFrames(t *testing.T, e *ecs.ECS, atlas *Atlas, renderable *Renderable, sprites ...string) {
+	t.Helper()
+
+	for i, sprite := range sprites {
+		stepWithin(t, e, 1, 0.25)
+		if got := renderable.Sprite.Frame(); got != atlas.Frames[sprite] {
+			t.Errorf("after %d updates, showing %v, want %s at %v", i+1, got, sprite, atlas.Frames[sprite])
+		}
+	}
+}
+
+func TestLoadAtlasClips(t *testing.T) {
+	atlas := loadAnimatedAtlas(t)
+
+	modes := map[string]AnimationMode{"pulse": AnimationPingPong, "spin": AnimationLoop, "pop": AnimationOnce}
+	for name, mode := range modes {
+		clip, ok := atlas.Clips[name]
+		if !ok {
+			t.Errorf("atlas has no clip %q", name)
+			continue
+		}
+		if clip.Mode != mode {
+			t.Errorf("clip %q has mode %q, want %q", name, clip.Mode, mode)
+		}
+	}
+
+	if frame := atlas.Clips["pulse"].Frames[1]; frame.Frame != atlas.Frames["star-core"] || frame.Duration != 0.25 {
+		t.Errorf("second frame of pulse is %v, want star-core for 0.25 seconds", frame)
+	}
+}
+
+func TestAnimationSystemPlaysClips(t *testing.T) {
+	atlas := loadAnimatedAtlas(t)
+
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+	AnimationSystem(&e)
+	finished := newRecordEvents(&e, AnimationFinishedEvent{})
+
+	renderable, _ := atlas.Renderable("bullet")
+	animation, err := atlas.Animation("pulse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := e.AddEntity(&Transform{}, renderable, animation)
+
+	// The first frame is shown as soon as the entity is added.
+	stepWithin(t, &e, 1, 0)
+	if renderable.Sprite.Frame() != atlas.Frames["star"] {
+		t.Fatalf("showing %v, want the first frame of pulse", renderable.Sprite.Frame())
+	}
+
+	playFrames(t, &e, atlas, renderable, "star-core", "bullet", "star-core", "star", "star-core")
+
+	e.PublishNextFrame(PlayAnimationEvent{ID: id, Clip: "spin"})
+	stepWithin(t, &e, 1, 0)
+	playFrames(t, &e, atlas, renderable, "star", "bullet", "bullet", "star")
+
+	e.PublishNextFrame(PlayAnimationEvent{ID: id, Clip: "pop"})
+	stepWithin(t, &e, 1, 0)
+	playFrames(t, &e, atlas, renderable, "bullet", "bullet", "bullet")
+
+	if events := finished.get(); len(events) != 1 || events[0] != (AnimationFinishedEvent{id, "pop"}) {
+		t.Errorf("got %v, want pop to finish once", events)
+	}
+	if !animation.Finished {
+		t.Error("pop didn't stay finished")
+	}
+}
+
+func TestAnimationSystemManyFinishes(t *testing.T) {
+	atlas := loadAnimatedAtlas(t)
+
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+	AnimationSystem(&e)
+	finished := newRecordEvents(&e, AnimationFinishedEvent{})
+
+	// Only a few entities can be added before each update.
+	var ids []ecs.EntityID
+	for i := 0; i < 120; i++ {
+		renderable, _ := atlas.Renderable("star")
+		animation, _ := atlas.Animation("pop")
+		ids = append(ids, e.AddEntity(renderable, animation))
+
+		if i%40 == 39 {
+			stepWithin(t, &e, 1, 0)
+		}
+	}
+
+	// Every clip finishes in the same update.
+	stepWithin(t, &e, 2, 0.25)
+
+	events := finished.get()
+	if len(events) != len(ids) {
+		t.Fatalf("got %d finishes, want %d", len(events), len(ids))
+	}
+	for i, event := range events {
+		if event.(AnimationFinishedEvent).ID != ids[i] {
+			t.Fatalf("finish %d was for %v, want %v", i, event.(AnimationFinishedEvent).ID, ids[i])
+		}
+	}
+}
+
+func TestAnimatingCopyLeavesOriginal(t *testing.T) {
+	atlas := loadAnimatedAtlas(t)
+
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+	AnimationSystem(&e)
+
+	template, _ := atlas.Renderable("star")
+	animation, _ := atlas.Animation("pop")
+	copied := template.Copy()
+	e.AddEntity(copied, animation)
+	stepWithin(t, &e, 2, 0.25)
+
+	if copied.Sprite.Frame() != atlas.Frames["bullet"] || template.Sprite.Frame() != atlas.Frames["star"] {
+		t.Errorf("copy shows %v and original %v, want only the copy animated", copied.Sprite.Frame(),
+			template.Sprite.Frame())
+	}
+}
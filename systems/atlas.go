@@ -0,0 +1,147 @@
+package systems
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faiface/pixel"
+	"os"
+	"path/filepath"
+)
+
+// Atlas is a picture containing many sprites, each of which has a name.
+type Atlas struct {
+	Picture pixel.Picture
+	Frames  map[string]pixel.Rect // The area of the picture taken up by each sprite, by name.
+	Clips   map[string]*Clip      // Animation clips made up of the atlas's sprites, by name.
+
+	path string
+}
+
+// atlasFile is the format of an atlas definition. The picture's path may be relative to the definition file.
+type atlasFile struct {
+	Picture string                 `json:"picture"`
+	Sprites map[string]atlasRegion `json:"sprites"`
+	Clips   map[string]atlasClip   `json:"clips"`
+}
+
+// atlasRegion is the area of a single sprite, measured in pixels from the bottom-left corner of the picture.
+type atlasRegion struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// atlasClip is an animation clip, with frames referring to sprites by name.
+type atlasClip struct {
+	Mode   AnimationMode `json:"mode"`
+	Frames []struct {
+		Sprite   string  `json:"sprite"`
+		Duration float64 `json:"duration"`
+	} `json:"frames"`
+}
+
+// LoadAtlas reads an atlas definition from the JSON file at the given path, and loads the picture it refers to, e.g.:
+//
+//	{
+//	  "picture": "sprites.png",
+//	  "sprites": {"bullet": {"x": 69, "y": 28, "width": 8, "height": 8}, ...},
+//	  "clips": {"spin": {"mode": "loop", "frames": [{"sprite": "bullet", "duration": 0.1}, ...]}}
+//	}
+//
+// Every sprite must lie within the picture, and every clip frame must refer to a sprite and last for some time.
+func LoadAtlas(path string) (*Atlas, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file atlasFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("atlas %s: %w", path, err)
+	}
+
+	picturePath := file.Picture
+	if !filepath.IsAbs(picturePath) {
+		picturePath = filepath.Join(filepath.Dir(path), picturePath)
+	}
+
+	picture, err := LoadPicture(picturePath)
+	if err != nil {
+		return nil, fmt.Errorf("atlas %s: %w", path, err)
+	}
+
+	atlas := &Atlas{Picture: picture, Frames: make(map[string]pixel.Rect), Clips: make(map[string]*Clip), path: path}
+
+	for name, region := range file.Sprites {
+		frame := pixel.R(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
+
+		if region.Width <= 0 || region.Height <= 0 || picture.Bounds().Intersect(frame) != frame {
+			return nil, fmt.Errorf("atlas %s: sprite %q at %v is not within the picture's bounds %v", path, name,
+				frame, picture.Bounds())
+		}
+
+		atlas.Frames[name] = frame
+	}
+
+	for name, saved := range file.Clips {
+		clip := &Clip{Mode: saved.Mode}
+
+		switch saved.Mode {
+		case AnimationLoop, AnimationOnce, AnimationPingPong:
+		case "":
+			clip.Mode = AnimationLoop
+		default:
+			return nil, fmt.Errorf("atlas %s: clip %q has unknown mode %q", path, name, saved.Mode)
+		}
+
+		if len(saved.Frames) == 0 {
+			return nil, fmt.Errorf("atlas %s: clip %q has no frames", path, name)
+		}
+
+		for i, frame := range saved.Frames {
+			region, ok := atlas.Frames[frame.Sprite]
+			if !ok {
+				return nil, fmt.Errorf("atlas %s: frame %d of clip %q refers to unknown sprite %q", path, i, name, frame.Sprite)
+			}
+			if frame.Duration <= 0 {
+				return nil, fmt.Errorf("atlas %s: frame %d of clip %q must have a positive duration", path, i, name)
+			}
+
+			clip.Frames = append(clip.Frames, ClipFrame{region, frame.Duration})
+		}
+
+		atlas.Clips[name] = clip
+	}
+
+	return atlas, nil
+}
+
+// Sprite returns a new sprite for the region with the given name.
+func (a *Atlas) Sprite(name string) (*pixel.Sprite, error) {
+	frame, ok := a.Frames[name]
+	if !ok {
+		return nil, fmt.Errorf("atlas %s has no sprite named %q", a.path, name)
+	}
+
+	return pixel.NewSprite(a.Picture, frame), nil
+}
+
+// Renderable returns a new Renderable for the sprite with the given name.
+func (a *Atlas) Renderable(name string) (*Renderable, error) {
+	sprite, err := a.Sprite(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Renderable{Sprite: sprite, Name: name}, nil
+}
+
+// Animation returns a new Animation which plays the atlas's clips, starting with the clip of the given name.
+func (a *Atlas) Animation(playing string) (*Animation, error) {
+	if _, ok := a.Clips[playing]; !ok {
+		return nil, fmt.Errorf("atlas %s has no clip named %q", a.path, playing)
+	}
+
+	return &Animation{Clips: a.Clips, Playing: playing}, nil
+}
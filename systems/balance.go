@@ -1,8 +1,8 @@
 package systems
 
 import (
+	"errors"
 	"github.com/emctague/go-loopy/ecs"
-	"log"
 )
 
 // Wallet is a component which stores the monetary balance of an entity.
@@ -12,39 +12,31 @@ type Wallet struct {
 
 // BalanceChangeEvent represents a change in the balance of an entity's wallet.
 type BalanceChangeEvent struct {
-	ID     uint64
+	ID     ecs.EntityID
 	Change int
 }
 
+// TargetEntity returns the entity whose balance is changing.
+func (b BalanceChangeEvent) TargetEntity() ecs.EntityID {
+	return b.ID
+}
+
 // BalanceSystem handles wallets and balance change events, keeping track of in-game currency.
+// Wallets are looked up in the ECS's component store, so this system doesn't need to track entities itself.
 func BalanceSystem(e *ecs.ECS) {
-	wallets := make(map[uint64]struct {
-		*Wallet
-	})
-
-	events := e.Subscribe()
-
-	go func() {
+	events := e.SubscribeTo(BalanceChangeEvent{})
 
-		for ev := range events {
-			switch event := ev.Event.(type) {
+	go e.HandleEvents("BalanceSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
 
-			case ecs.EntityAddedEvent:
-				ecs.UnpackEntity(event, &wallets)
-
-			case ecs.EntityRemovedEvent:
-				ecs.RemoveEntity(event.ID, &wallets)
-
-			case BalanceChangeEvent:
-				wallet, ok := wallets[event.ID]
-				if !ok {
-					log.Fatal("Trying to change balance of nonexistent wallet")
-				}
-
-				wallet.Balance += event.Change
+		case BalanceChangeEvent:
+			wallet, ok := ecs.ComponentOf[Wallet](e, event.ID)
+			if !ok {
+				e.ReportError("BalanceSystem", event, errors.New("trying to change balance of nonexistent wallet"))
+				break
 			}
 
-			ev.Done()
+			wallet.Balance += event.Change
 		}
-	}()
+	})
 }
@@ -14,51 +14,58 @@ type Enemy struct {
 
 type eBullet struct {
 	*Transform
+	*Collider
 	*Bullet
 }
 
-type eEnemy struct {
-	*Transform
-	*Enemy
-}
+// BulletSystem damages enemies when bullets hit them, using up the bullet. Each bullet's collider is swept along the
+// path it moved in the update, so that fast bullets can't pass through enemies between updates. If index isn't nil,
+// only enemies near each bullet's path according to the index are tested.
+func BulletSystem(e *ecs.ECS, index *SpatialIndex) {
+	bullets := make(map[ecs.EntityID]eBullet)
 
-// BulletSystem handles gunshot collisions
-func BulletSystem(e *ecs.ECS) {
-	bullets := make(map[uint64]eBullet)
-	enemies := make(map[uint64]eEnemy)
-	events := e.Subscribe()
+	// Bullets which have hit something and enemies which have died, which haven't been removed yet, so that they can't
+	// be hit again.
+	spent := make(map[ecs.EntityID]bool)
 
-	go func() {
-		for ev := range events {
-			switch event := ev.Event.(type) {
-			case ecs.EntityAddedEvent:
-				ecs.UnpackEntity(event, &bullets)
-				ecs.UnpackEntity(event, &enemies)
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ecs.LateUpdateEvent{})
 
-			case ecs.EntityRemovedEvent:
-				ecs.RemoveEntity(event.ID, &bullets)
-				ecs.RemoveEntity(event.ID, &enemies)
+	go e.HandleEvents("BulletSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			ecs.RefreshEntityOf(event, bullets)
 
-			case ecs.UpdateBeginEvent:
-				for bid, bullet := range bullets {
-					for eid, enemy := range enemies {
-						// eww why
-						if bullet.X > enemy.X-enemy.Width/2 && bullet.X < enemy.X+enemy.Width/2 && bullet.Y > enemy.Y-enemy.Height/2 && bullet.Y < enemy.Y+enemy.Height/2 {
-							enemy.Health--
-							e.RemoveEntity(bid)
+		case ecs.EntityRemovedEvent:
+			delete(bullets, event.ID)
+			delete(spent, event.ID)
 
-							if enemy.Health < 1 {
-								e.RemoveEntity(eid)
-							}
+		case ecs.LateUpdateEvent:
+			for _, bid := range sortedIDs(bullets) {
+				bullet := bullets[bid]
+				if spent[bid] {
+					continue
+				}
 
-							break
-						}
-					}
+				hit, ok := ShapeCast(e, index, bullet.Collider, bullet.Transform, bullet.lastPosition(),
+					bullet.World.Position(), func(id ecs.EntityID) bool {
+						_, isEnemy := ecs.ComponentOf[Enemy](e, id)
+						return isEnemy && !spent[id]
+					})
+				if !ok {
+					continue
 				}
 
-			}
+				enemy, _ := ecs.ComponentOf[Enemy](e, hit.ID)
+				enemy.Health--
+				spent[bid] = true
+				e.RemoveEntity(bid)
 
-			ev.Wg.Done()
+				if enemy.Health < 1 {
+					spent[hit.ID] = true
+					e.RemoveEntity(hit.ID)
+				}
+			}
 		}
-	}()
+	})
 }
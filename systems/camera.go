@@ -0,0 +1,140 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"math"
+	"reflect"
+)
+
+// Camera is a component which determines which part of the world is drawn. The view is centered on the camera's
+// position, and may be zoomed and rotated. If several entities have cameras, the one with the lowest ID is used.
+// Without any camera, world coordinates are drawn as screen coordinates.
+type Camera struct {
+	X        float64 // The X position at the center of the view.
+	Y        float64 // The Y position at the center of the view.
+	Zoom     float64 // How many screen pixels one world unit takes up. Zero is treated as 1.
+	Rotation float64 // The rotation of the view, counter-clockwise.
+
+	Target         ecs.EntityID // An entity with a Transform for the camera to follow, or 0 to stay in place.
+	DeadZoneWidth  float64      // The width of the area around the view's center in which the target may move freely.
+	DeadZoneHeight float64      // The height of the area around the view's center in which the target may move freely.
+	Smoothing      float64      // Seconds the camera takes to cover most of the distance to its target. Zero snaps.
+
+	PrevX        float64 // The X position at the end of the previous update, used for interpolation.
+	PrevY        float64 // The Y position at the end of the previous update, used for interpolation.
+	PrevRotation float64 // The rotation at the end of the previous update, used for interpolation.
+}
+
+// Matrix returns the matrix which projects world coordinates onto a viewport of the given bounds, with the camera's
+// position blended between the end of the previous update (alpha = 0) and its current state (alpha = 1).
+func (c *Camera) Matrix(viewport pixel.Rect, alpha float64) pixel.Matrix {
+	x := c.PrevX + (c.X-c.PrevX)*alpha
+	y := c.PrevY + (c.Y-c.PrevY)*alpha
+	rotation := c.PrevRotation + math.Remainder(c.Rotation-c.PrevRotation, 2*math.Pi)*alpha
+
+	zoom := c.Zoom
+	if zoom == 0 {
+		zoom = 1
+	}
+
+	return pixel.IM.Moved(pixel.V(-x, -y)).Rotated(pixel.ZV, -rotation).Scaled(pixel.ZV, zoom).Moved(viewport.Center())
+}
+
+// ScreenToWorld converts a position within the viewport, such as the mouse position, to world coordinates.
+func (c *Camera) ScreenToWorld(viewport pixel.Rect, screen pixel.Vec) pixel.Vec {
+	return c.Matrix(viewport, 1).Unproject(screen)
+}
+
+// ActiveCamera returns the camera which the world is viewed through, if there is one.
+func ActiveCamera(e *ecs.ECS) (*Camera, bool) {
+	ids := e.Query(reflect.TypeOf(&Camera{}))
+	if len(ids) == 0 {
+		return nil, false
+	}
+
+	return ecs.ComponentOf[Camera](e, ids[0])
+}
+
+// MouseWorldPosition returns the position of the mouse in world coordinates, as seen through the active camera.
+func MouseWorldPosition(e *ecs.ECS, in input.Input) pixel.Vec {
+	camera, ok := ActiveCamera(e)
+	if !ok {
+		return in.MousePosition()
+	}
+
+	return camera.ScreenToWorld(in.Bounds(), in.MousePosition())
+}
+
+// ViewBounds returns the area of the world which is visible within a viewport of the given bounds. If the camera is
+// rotated, this is the smallest rectangle containing the whole view.
+func ViewBounds(e *ecs.ECS, viewport pixel.Rect) pixel.Rect {
+	camera, ok := ActiveCamera(e)
+	if !ok {
+		return viewport
+	}
+
+	view := pixel.Rect{Min: pixel.V(math.Inf(1), math.Inf(1)), Max: pixel.V(math.Inf(-1), math.Inf(-1))}
+	for _, corner := range viewport.Vertices() {
+		world := camera.ScreenToWorld(viewport, corner)
+		view.Min = pixel.V(math.Min(view.Min.X, world.X), math.Min(view.Min.Y, world.Y))
+		view.Max = pixel.V(math.Max(view.Max.X, world.X), math.Max(view.Max.Y, world.Y))
+	}
+
+	return view
+}
+
+type eCamera struct{ *Camera }
+
+// CameraSystem moves cameras to follow their targets at the end of every update, once the transform system has worked
+// out where the targets are. The target is kept within the camera's dead zone, with the camera easing towards it
+// according to its smoothing.
+func CameraSystem(e *ecs.ECS) {
+	cameras := make(map[ecs.EntityID]eCamera)
+
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, TransformsRefreshedEvent{})
+
+	go e.HandleEvents("CameraSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			id, _ := event.ChangedEntity()
+			_, tracked := cameras[id]
+
+			// New cameras shouldn't be interpolated from the origin.
+			if camera := ecs.RefreshEntityOf(event, cameras); camera != nil && !tracked {
+				camera.PrevX, camera.PrevY, camera.PrevRotation = camera.X, camera.Y, camera.Rotation
+			}
+
+		case ecs.EntityRemovedEvent:
+			delete(cameras, event.ID)
+
+		case TransformsRefreshedEvent:
+			for _, camera := range cameras {
+				camera.PrevX, camera.PrevY, camera.PrevRotation = camera.X, camera.Y, camera.Rotation
+
+				target, ok := ecs.ComponentOf[Transform](e, camera.Target)
+				if !ok {
+					continue
+				}
+
+				goalX := follow(camera.X, target.World.X, camera.DeadZoneWidth/2)
+				goalY := follow(camera.Y, target.World.Y, camera.DeadZoneHeight/2)
+
+				ease := 1.0
+				if camera.Smoothing > 0 {
+					ease = 1 - math.Exp(-event.Delta/camera.Smoothing)
+				}
+
+				camera.X += (goalX - camera.X) * ease
+				camera.Y += (goalY - camera.Y) * ease
+			}
+		}
+	})
+}
+
+// follow returns the closest position to current which is within reach of target.
+func follow(current float64, target float64, reach float64) float64 {
+	return math.Max(target-reach, math.Min(target+reach, current))
+}
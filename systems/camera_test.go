@@ -0,0 +1,30 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"testing"
+)
+
+func TestCameraFollowsChangedTarget(t *testing.T) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+	TransformSystem(&e, nil)
+	CameraSystem(&e)
+
+	target := &Transform{}
+	targetID := e.AddEntity(target)
+	camera := &Camera{Target: targetID}
+	e.AddEntity(camera)
+	stepWithin(t, &e, 1, 0)
+
+	// The target is moved directly, as the player system does, and the camera follows its new world position.
+	for i := 1; i <= 3; i++ {
+		target.X, target.Y = float64(i*10), float64(i*5)
+		stepWithin(t, &e, 1, 1.0/60)
+
+		if camera.X != target.X || camera.Y != target.Y {
+			t.Errorf("after moving the target to %v, %v, camera is at %v, %v", target.X, target.Y, camera.X, camera.Y)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"sort"
+)
+
+// ColliderShape is the shape of a Collider.
+type ColliderShape int
+
+const (
+	ShapeAABB   ColliderShape = iota // An axis-aligned box, which doesn't rotate with its transform.
+	ShapeCircle                      // A circle.
+)
+
+// Collision layers used by the game. A collider with no layer is on CollisionLayerDefault.
+const (
+	CollisionLayerDefault uint32 = 1 << iota
+	CollisionLayerPlayer
+	CollisionLayerEnemy
+	CollisionLayerBullet
+)
+
+// Collider is a component which gives an entity a shape for the collision system, centered on its Transform.
+type Collider struct {
+	Shape   ColliderShape
+	Width   float64 // The width of an AABB. If zero, the Transform's width is used.
+	Height  float64 // The height of an AABB. If zero, the Transform's height is used.
+	Radius  float64 // The radius of a circle.
+	OffsetX float64 // Horizontal offset of the shape's center from the Transform.
+	OffsetY float64 // Vertical offset of the shape's center from the Transform.
+
+	Layer uint32 // Bits for the layers this collider is on. Zero means CollisionLayerDefault.
+	Mask  uint32 // Bits for the layers this collider collides with. Zero means every layer.
+
+	// Triggers report collisions, but are never pushed apart from other colliders. Two colliders which aren't
+	// triggers are solid, and are pushed apart if either of them has Physics.
+	Trigger bool
+}
+
+// Collision describes two colliders which overlap.
+type Collision struct {
+	A      ecs.EntityID // The entity with the lower ID.
+	B      ecs.EntityID // The entity with the higher ID.
+	Normal pixel.Vec    // The direction in which B would need to move to stop overlapping A.
+	Depth  float64      // How far B would need to move along Normal to stop overlapping A.
+}
+
+// Other returns whichever of the two entities isn't the given one.
+func (c Collision) Other(id ecs.EntityID) ecs.EntityID {
+	if c.A == id {
+		return c.B
+	}
+	return c.A
+}
+
+// CollisionEnterEvent is published when two colliders start overlapping.
+type CollisionEnterEvent struct{ Collision }
+
+// CollisionStayEvent is published every update in which two colliders carry on overlapping, after the first.
+type CollisionStayEvent struct{ Collision }
+
+// CollisionExitEvent is published when two colliders stop overlapping, including when one of them is removed.
+type CollisionExitEvent struct {
+	A ecs.EntityID
+	B ecs.EntityID
+}
+
+// layers returns the collider's layer and mask with their defaults applied.
+func (c *Collider) layers() (layer uint32, mask uint32) {
+	layer, mask = c.Layer, c.Mask
+	if layer == 0 {
+		layer = CollisionLayerDefault
+	}
+	if mask == 0 {
+		mask = math.MaxUint32
+	}
+	return
+}
+
+// collidesWith returns true if both colliders' masks include the other's layer.
+func (c *Collider) collidesWith(other *Collider) bool {
+	layer, mask := c.layers()
+	otherLayer, otherMask := other.layers()
+	return layer&otherMask != 0 && otherLayer&mask != 0
+}
+
+// reach returns how far the collider extends from its transform's position along either axis.
+func (c *Collider) reach(transform *Transform) float64 {
+	if c.Shape == ShapeCircle {
+		return math.Max(math.Abs(c.OffsetX), math.Abs(c.OffsetY)) + c.Radius
+	}
+	size := c.Size(transform)
+	return math.Max(math.Abs(c.OffsetX)+size.X/2, math.Abs(c.OffsetY)+size.Y/2)
+}
+
+// Center returns the world position of the center of the collider's shape.
+func (c *Collider) Center(transform *Transform) pixel.Vec {
+	return transform.World.Position().Add(pixel.V(c.OffsetX, c.OffsetY))
+}
+
+// Size returns the width and height of an AABB collider.
+func (c *Collider) Size(transform *Transform) pixel.Vec {
+	size := pixel.V(c.Width, c.Height)
+	if size.X == 0 {
+		size.X = transform.Width
+	}
+	if size.Y == 0 {
+		size.Y = transform.Height
+	}
+	return size
+}
+
+// Contains returns true if the given point is within the collider.
+func (c *Collider) Contains(transform *Transform, point pixel.Vec) bool {
+	center := c.Center(transform)
+
+	if c.Shape == ShapeCircle {
+		return point.To(center).Len() <= c.Radius
+	}
+
+	half := c.Size(transform).Scaled(0.5)
+	return math.Abs(point.X-center.X) <= half.X && math.Abs(point.Y-center.Y) <= half.Y
+}
+
+// contactSlop is how far apart two colliders may be while still counting as touching. Solid colliders are pushed apart
+// until they exactly touch, so without this, bodies resting against each other would stop colliding.
+const contactSlop = 1e-6
+
+// overlap tests two colliders against each other, returning the direction and distance which b would need to move to
+// stop overlapping a.
+func overlap(a *Collider, ta *Transform, b *Collider, tb *Transform) (normal pixel.Vec, depth float64, ok bool) {
+	switch {
+	case a.Shape == ShapeCircle && b.Shape == ShapeCircle:
+		return overlapCircles(a.Center(ta), a.Radius, b.Center(tb), b.Radius)
+
+	case a.Shape == ShapeCircle:
+		normal, depth, ok = overlapBoxCircle(b.Center(tb), b.Size(tb), a.Center(ta), a.Radius)
+		return normal.Scaled(-1), depth, ok
+
+	case b.Shape == ShapeCircle:
+		return overlapBoxCircle(a.Center(ta), a.Size(ta), b.Center(tb), b.Radius)
+
+	default:
+		return overlapBoxes(a.Center(ta), a.Size(ta), b.Center(tb), b.Size(tb))
+	}
+}
+
+// overlapBoxes tests two AABBs, pushing b out along whichever axis it overlaps a the least.
+func overlapBoxes(centerA pixel.Vec, sizeA pixel.Vec, centerB pixel.Vec, sizeB pixel.Vec) (pixel.Vec, float64, bool) {
+	d := centerB.Sub(centerA)
+	overlapX := (sizeA.X+sizeB.X)/2 - math.Abs(d.X)
+	overlapY := (sizeA.Y+sizeB.Y)/2 - math.Abs(d.Y)
+
+	if overlapX < -contactSlop || overlapY < -contactSlop {
+		return pixel.ZV, 0, false
+	}
+
+	if overlapX < overlapY {
+		return pixel.V(sign(d.X), 0), math.Max(overlapX, 0), true
+	}
+	return pixel.V(0, sign(d.Y)), math.Max(overlapY, 0), true
+}
+
+// overlapCircles tests two circles, pushing b directly away from a.
+func overlapCircles(centerA pixel.Vec, radiusA float64, centerB pixel.Vec, radiusB float64) (pixel.Vec, float64, bool) {
+	d := centerB.Sub(centerA)
+	distance := d.Len()
+
+	if distance > radiusA+radiusB+contactSlop {
+		return pixel.ZV, 0, false
+	}
+
+	if distance == 0 {
+		return pixel.V(0, 1), radiusA + radiusB, true
+	}
+	return d.Scaled(1 / distance), math.Max(radiusA+radiusB-distance, 0), true
+}
+
+// overlapBoxCircle tests an AABB against a circle, pushing the circle away from the closest point on the box.
+func overlapBoxCircle(center pixel.Vec, size pixel.Vec, circle pixel.Vec, radius float64) (pixel.Vec, float64, bool) {
+	half := size.Scaled(0.5)
+	d := circle.Sub(center)
+
+	// If the circle's center is inside the box, push it out along the shallowest axis.
+	if math.Abs(d.X) < half.X && math.Abs(d.Y) < half.Y {
+		overlapX := half.X - math.Abs(d.X)
+		overlapY := half.Y - math.Abs(d.Y)
+
+		if overlapX < overlapY {
+			return pixel.V(sign(d.X), 0), overlapX + radius, true
+		}
+		return pixel.V(0, sign(d.Y)), overlapY + radius, true
+	}
+
+	closest := pixel.V(math.Max(-half.X, math.Min(half.X, d.X)), math.Max(-half.Y, math.Min(half.Y, d.Y)))
+	offset := d.Sub(closest)
+	distance := offset.Len()
+
+	if distance > radius+contactSlop {
+		return pixel.ZV, 0, false
+	}
+
+	// The circle's center is on the edge of the box.
+	if distance == 0 {
+		if half.X-math.Abs(d.X) < half.Y-math.Abs(d.Y) {
+			return pixel.V(sign(d.X), 0), radius, true
+		}
+		return pixel.V(0, sign(d.Y)), radius, true
+	}
+	return offset.Scaled(1 / distance), math.Max(radius-distance, 0), true
+}
+
+// sign returns -1 for negative numbers and 1 otherwise.
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+type eCollider struct {
+	*Transform
+	*Collider
+}
+
+// collisionPair identifies two colliding entities, with the lower ID first.
+type collisionPair struct {
+	a, b ecs.EntityID
+}
+
+// CollisionSystem tests every pair of colliders once everything has moved in an update, publishing enter, stay and
+// exit events for each pair, and pushing solid colliders with Physics out of each other. If index isn't nil, only
+// colliders near each other according to the index are tested.
+func CollisionSystem(e *ecs.ECS, index *SpatialIndex) {
+	colliders := make(map[ecs.EntityID]eCollider)
+	touching := make(map[collisionPair]bool)
+
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ecs.LateUpdateEvent{})
+
+	go e.HandleEvents("CollisionSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			ecs.RefreshEntity(event, &colliders)
+
+			// Record how far the collider reaches, so that casts can find it before the next update's collisions.
+			if index != nil {
+				id, _ := event.ChangedEntity()
+				if collider, ok := colliders[id]; ok {
+					index.SetExtent(id, collider.reach(collider.Transform))
+				} else {
+					index.RemoveExtent(id)
+				}
+			}
+
+		case ecs.EntityRemovedEvent:
+			ecs.RemoveEntity(event.ID, &colliders)
+			if index != nil {
+				index.RemoveExtent(event.ID)
+			}
+
+		case ecs.LateUpdateEvent:
+			ids := sortedIDs(colliders)
+			stillTouching := make(map[collisionPair]bool)
+
+			// Every pair may cause events, so they are published together once all pairs have been tested.
+			var published ecs.EventBatch
+			pushes := make(map[ecs.EntityID]pixel.Vec)
+
+			// The furthest any collider reaches bounds how far apart two touching colliders' positions can be.
+			// Colliders may have changed size, so their extents in the index are brought up to date too.
+			var maxReach float64
+			for id, collider := range colliders {
+				reach := collider.reach(collider.Transform)
+				maxReach = math.Max(maxReach, reach)
+				if index != nil {
+					index.SetExtent(id, reach)
+				}
+			}
+
+			for i, idA := range ids {
+				a := colliders[idA]
+				candidates := ids[i+1:]
+				if index != nil {
+					distance := a.reach(a.Transform) + maxReach
+					candidates = index.QueryRect(pixel.R(a.World.X-distance, a.World.Y-distance, a.World.X+distance, a.World.Y+distance))
+				}
+
+				for _, idB := range candidates {
+					b, ok := colliders[idB]
+					if !ok || idB <= idA {
+						continue
+					}
+					if !a.collidesWith(b.Collider) {
+						continue
+					}
+
+					normal, depth, ok := overlap(a.Collider, a.Transform, b.Collider, b.Transform)
+					if !ok {
+						continue
+					}
+
+					collision := Collision{idA, idB, normal, depth}
+					pair := collisionPair{idA, idB}
+					stillTouching[pair] = true
+
+					if touching[pair] {
+						published = append(published, CollisionStayEvent{collision})
+					} else {
+						published = append(published, CollisionEnterEvent{collision})
+					}
+
+					if !a.Trigger && !b.Trigger {
+						resolve(e, collision, pushes)
+					}
+				}
+			}
+
+			for _, pair := range sortedPairs(touching) {
+				if !stillTouching[pair] {
+					published = append(published, CollisionExitEvent{pair.a, pair.b})
+				}
+			}
+
+			// Each body is moved once, by the sum of the pushes from everything it collided with.
+			for _, id := range sortedIDs(pushes) {
+				published = append(published, TransformEvent{id, pushes[id].X, pushes[id].Y, false})
+			}
+
+			touching = stillTouching
+			if len(published) > 0 {
+				ev.Next <- published
+			}
+		}
+	})
+}
+
+// resolve bounces two solid colliders off each other, and adds the pushes which move them apart to pushes. Entities
+// without Physics don't move, and otherwise lighter entities are moved further than heavier ones.
+func resolve(e *ecs.ECS, collision Collision, pushes map[ecs.EntityID]pixel.Vec) {
+	physicsA, movesA := ecs.ComponentOf[Physics](e, collision.A)
+	physicsB, movesB := ecs.ComponentOf[Physics](e, collision.B)
+
+	var inverseMassA, inverseMassB float64
+	if movesA {
+		inverseMassA = physicsA.inverseMass()
+	}
+	if movesB {
+		inverseMassB = physicsB.inverseMass()
+	}
+
+	totalInverseMass := inverseMassA + inverseMassB
+	if totalInverseMass <= 0 {
+		return
+	}
+
+	if movesA {
+		push := collision.Normal.Scaled(-collision.Depth * inverseMassA / totalInverseMass)
+		pushes[collision.A] = pushes[collision.A].Add(push)
+	}
+
+	if movesB {
+		push := collision.Normal.Scaled(collision.Depth * inverseMassB / totalInverseMass)
+		pushes[collision.B] = pushes[collision.B].Add(push)
+	}
+
+	bounce(physicsA, inverseMassA, physicsB, inverseMassB, collision.Normal)
+}
+
+// bounce applies the impulses which stop two bodies moving into each other along the normal, which points from a to b.
+// Their restitution makes them bounce apart, and their friction slows them sliding along each other. Either body may
+// be nil if it has no Physics, in which case its inverse mass must be 0.
+func bounce(a *Physics, inverseMassA float64, b *Physics, inverseMassB float64, normal pixel.Vec) {
+	var velocityA, velocityB pixel.Vec
+	var restitution, friction float64
+	switch {
+	case a != nil && b != nil:
+		velocityA, velocityB = pixel.V(a.VelX, a.VelY), pixel.V(b.VelX, b.VelY)
+		restitution, friction = math.Max(a.Restitution, b.Restitution), math.Sqrt(a.Friction*b.Friction)
+	case a != nil:
+		velocityA = pixel.V(a.VelX, a.VelY)
+		restitution, friction = a.Restitution, a.Friction
+	case b != nil:
+		velocityB = pixel.V(b.VelX, b.VelY)
+		restitution, friction = b.Restitution, b.Friction
+	}
+
+	relative := velocityB.Sub(velocityA)
+	closing := relative.Dot(normal)
+	if closing >= 0 {
+		return
+	}
+
+	totalInverseMass := inverseMassA + inverseMassB
+	impulse := normal.Scaled(-(1 + restitution) * closing / totalInverseMass)
+
+	// Friction can take away no more than all of the sliding speed, and no more than the normal impulse allows.
+	tangent := relative.Sub(normal.Scaled(closing))
+	if sliding := tangent.Len(); sliding > 0 {
+		frictionImpulse := math.Min(sliding/totalInverseMass, friction*impulse.Len())
+		impulse = impulse.Sub(tangent.Scaled(frictionImpulse / sliding))
+	}
+
+	if a != nil {
+		velocityA = velocityA.Sub(impulse.Scaled(inverseMassA))
+		a.VelX, a.VelY = velocityA.X, velocityA.Y
+	}
+	if b != nil {
+		velocityB = velocityB.Add(impulse.Scaled(inverseMassB))
+		b.VelX, b.VelY = velocityB.X, velocityB.Y
+	}
+}
+
+// sortedPairs returns the pairs in the given set in order, so that events are published in the same order every time.
+func sortedPairs(pairs map[collisionPair]bool) []collisionPair {
+	sorted := make([]collisionPair, 0, len(pairs))
+	for pair := range pairs {
+		sorted = append(sorted, pair)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].a != sorted[j].a {
+			return sorted[i].a < sorted[j].a
+		}
+		return sorted[i].b < sorted[j].b
+	})
+	return sorted
+}
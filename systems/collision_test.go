@@ -0,0 +1,116 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stepWithin steps the ECS, failing the test if the steps don't finish in time or any errors are reported.
+func stepWithin(t *testing.T, e *ecs.ECS, n int, delta float64) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Step(n, delta)
+	}()
+
+	select {
+	case err := <-done:
+		checkErrors(t, e, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("step did not finish")
+	}
+}
+
+// checkErrors fails the test if stepping or running the ECS returned an error, or if any systems reported errors.
+// The ECS should use ErrorPolicyCollect, so that the errors are kept.
+func checkErrors(tb testing.TB, e *ecs.ECS, err error) {
+	tb.Helper()
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if errs := e.Errors(); len(errs) != 0 {
+		tb.Fatalf("got errors %v", errs)
+	}
+}
+
+// countEvents counts the events of the given types published by the ECS.
+func countEvents(e *ecs.ECS, events ...interface{}) *int64 {
+	var count int64
+	go e.HandleEvents("EventCounter", e.SubscribeTo(events...), func(ev ecs.EventContainer) {
+		atomic.AddInt64(&count, 1)
+	})
+	return &count
+}
+
+func TestCollisionSystemManyPairs(t *testing.T) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+
+	index := NewSpatialIndex(64)
+	TransformSystem(&e, index)
+	CollisionSystem(&e, index)
+	enters := countEvents(&e, CollisionEnterEvent{})
+
+	// 12 colliders all overlapping each other make 66 pairs, more than Next can hold.
+	for i := 0; i < 12; i++ {
+		e.AddEntity(&Transform{X: float64(i)}, &Physics{DragFactor: 1}, &Collider{Shape: ShapeCircle, Radius: 20, Trigger: i%2 == 0})
+	}
+
+	stepWithin(t, &e, 2, 1.0/60)
+
+	if got := atomic.LoadInt64(enters); got != 66 {
+		t.Errorf("got %d enter events, want 66", got)
+	}
+}
+
+func TestCollisionSystemPushesApart(t *testing.T) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+
+	TransformSystem(&e, nil)
+	CollisionSystem(&e, nil)
+
+	wall := &Transform{X: 0, Width: 20, Height: 100}
+	e.AddEntity(wall, &Collider{})
+	body := &Transform{X: 15}
+	e.AddEntity(body, &Physics{VelX: -100, DragFactor: 1}, &Collider{Shape: ShapeCircle, Radius: 10})
+
+	stepWithin(t, &e, 1, 1.0/60)
+
+	if body.World.X < 20-contactSlop {
+		t.Errorf("body is at %v, inside the wall", body.World.X)
+	}
+	if wall.World.Position() != pixel.ZV {
+		t.Errorf("wall moved to %v", wall.World.Position())
+	}
+}
+
+func TestCollisionSystemParentedCollider(t *testing.T) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+
+	index := NewSpatialIndex(64)
+	TransformSystem(&e, index)
+	CollisionSystem(&e, index)
+	enters := countEvents(&e, CollisionEnterEvent{})
+
+	// The child's local position is far from where its parent's rotation puts it in the world.
+	parent := e.AddEntity(&Transform{X: 500, Rotation: math.Pi / 2})
+	e.AddEntity(&Transform{X: 100, ParentID: parent}, &Collider{Shape: ShapeCircle, Radius: 5, Trigger: true})
+	e.AddEntity(&Transform{X: 500, Y: 100}, &Collider{Shape: ShapeCircle, Radius: 5, Trigger: true})
+
+	stepWithin(t, &e, 2, 1.0/60)
+
+	if got := atomic.LoadInt64(enters); got != 1 {
+		t.Errorf("got %d enter events, want 1", got)
+	}
+}
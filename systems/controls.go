@@ -0,0 +1,45 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/input"
+	"strconv"
+)
+
+// Actions which the player can bind buttons to.
+const (
+	ActionMoveUp    input.Action = "MoveUp"
+	ActionMoveDown  input.Action = "MoveDown"
+	ActionMoveLeft  input.Action = "MoveLeft"
+	ActionMoveRight input.Action = "MoveRight"
+	ActionFire      input.Action = "Fire"
+	ActionDig       input.Action = "Dig"
+	ActionInteract  input.Action = "Interact"
+)
+
+// MenuChoices is the number of interaction menu choices which are bound by default.
+const MenuChoices = 9
+
+// ActionMenuChoice returns the action which selects the nth choice (counting from 1) in an interaction menu.
+func ActionMenuChoice(n int) input.Action {
+	return input.Action("MenuChoice" + strconv.Itoa(n))
+}
+
+// DefaultBindings returns the default bindings for every action: both the arrow keys and WASD for movement, the left
+// mouse button for firing and digging, space for interacting, and the number keys for menu choices.
+func DefaultBindings() *input.Bindings {
+	bindings := input.NewBindings()
+
+	bindings.Bind(ActionMoveUp, input.KeyUp, input.KeyW)
+	bindings.Bind(ActionMoveDown, input.KeyDown, input.KeyS)
+	bindings.Bind(ActionMoveLeft, input.KeyLeft, input.KeyA)
+	bindings.Bind(ActionMoveRight, input.KeyRight, input.KeyD)
+	bindings.Bind(ActionFire, input.MouseButtonLeft)
+	bindings.Bind(ActionDig, input.MouseButtonLeft)
+	bindings.Bind(ActionInteract, input.KeySpace)
+
+	for n := 1; n <= MenuChoices; n++ {
+		bindings.Bind(ActionMenuChoice(n), input.Key0+input.Button(n))
+	}
+
+	return bindings
+}
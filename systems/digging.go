@@ -2,7 +2,10 @@ package systems
 
 import (
 	"github.com/emctague/go-loopy/ecs"
-	"github.com/faiface/pixel/pixelgl"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"math"
+	"reflect"
 )
 
 // Diggable is a component attached to objects which can be broken in a way which resembles mining in games like
@@ -18,21 +21,78 @@ type eDiggable struct {
 	*Renderable
 }
 
-// DigSystem provides the ability for the player to click over an entity and eventually break it.
-var DigSystem = func(e *ecs.ECS, win *pixelgl.Window) {
-	ecs.BehaviorSystem(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID uint64, diggable eDiggable) {
+// minDigSize is how wide and tall the area that can be dug is for diggables without a Collider, however small their
+// Transform is.
+const minDigSize = 10
 
-		if win.Pressed(pixelgl.MouseButtonLeft) {
-			mp := win.MousePosition()
+// digShape returns the shape the mouse must be over to dig an entity: its Collider, or if it has none, a box covering
+// its Transform's bounds.
+func digShape(e *ecs.ECS, id ecs.EntityID, transform *Transform) *Collider {
+	if collider, ok := ecs.ComponentOf[Collider](e, id); ok {
+		return collider
+	}
+	return &Collider{Width: math.Max(transform.Width, minDigSize), Height: math.Max(transform.Height, minDigSize)}
+}
+
+// DigSystem provides the ability for the player to click over an entity's collider, or its Transform's bounds if it
+// has no collider, and eventually break it. If index isn't nil, only diggables near the mouse according to the index
+// are checked. The size of a diggable without a collider is read when it is added.
+func DigSystem(e *ecs.ECS, in input.Actions, index *SpatialIndex) {
+	diggables := make(map[ecs.EntityID]eDiggable)
+
+	// How far diggables without colliders reach, as the index only knows how far colliders reach.
+	unbounded := newExtentSet()
+
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ecs.UpdateBeginEvent{})
+
+	go e.HandleEvents("DigSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			id, components := event.ChangedEntity()
+			diggable := ecs.RefreshEntityOf(event, diggables)
+
+			if _, hasCollider := components[reflect.TypeOf(&Collider{})]; diggable != nil && !hasCollider {
+				unbounded.set(id, digShape(e, id, diggable.Transform).reach(diggable.Transform))
+			} else {
+				unbounded.remove(id)
+			}
+
+		case ecs.EntityRemovedEvent:
+			delete(diggables, event.ID)
+			unbounded.remove(event.ID)
+
+		case ecs.UpdateBeginEvent:
+			if !in.ActionPressed(ActionDig) {
+				break
+			}
+
+			mp := MouseWorldPosition(e, in)
+
+			candidates := sortedIDs(diggables)
+			if index != nil {
+				// A diggable under the mouse can be no further from it than its shape reaches.
+				reach := math.Max(index.MaxExtent(), unbounded.maximum())
+				candidates = index.QueryRect(pixel.R(mp.X-reach, mp.Y-reach, mp.X+reach, mp.Y+reach))
+			}
+
+			// Several diggables may break at once, so they are removed together.
+			var removed ecs.EventBatch
+			for _, id := range candidates {
+				diggable, ok := diggables[id]
+				if !ok || !digShape(e, id, diggable.Transform).Contains(diggable.Transform, mp) {
+					continue
+				}
 
-			if mp.X > diggable.X+5 || mp.X < diggable.X-5 || mp.Y > diggable.Y+5 || mp.Y < diggable.Y-5 {
-				return
+				diggable.Durability -= event.Delta
+				if diggable.Durability <= 0 {
+					removed = append(removed, ecs.EntityRemovedEvent{ID: id})
+				}
 			}
 
-			diggable.Durability -= delta
-			if diggable.Durability <= 0 {
-				ev.Next <- ecs.EntityRemovedEvent{ID: entityID}
+			if len(removed) > 0 {
+				ev.Next <- removed
 			}
 		}
-	})(e)
+	})
 }
@@ -0,0 +1,124 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"reflect"
+	"testing"
+)
+
+// newDigWorld returns an ECS running the dig system, along with the input which controls it.
+func newDigWorld(index *SpatialIndex) (*ecs.ECS, *input.Memory) {
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e := &world
+
+	memory := input.NewMemory(pixel.R(0, 0, 1024, 768))
+	TransformSystem(e, index)
+	CollisionSystem(e, index)
+	DigSystem(e, input.Actions{Input: memory, Bindings: DefaultBindings()}, index)
+	return e, memory
+}
+
+func TestDigWithoutCollider(t *testing.T) {
+	for _, index := range []*SpatialIndex{nil, NewSpatialIndex(64)} {
+		e, memory := newDigWorld(index)
+
+		crate := e.AddEntity(&Transform{X: 100, Y: 100, Width: 40, Height: 40}, &Diggable{1, 1}, &Renderable{})
+		pebble := e.AddEntity(&Transform{X: 300, Y: 300}, &Diggable{1, 1}, &Renderable{})
+		stepWithin(t, e, 1, 0)
+
+		// The mouse is over the crate's bounds, but well away from its position.
+		memory.MoveMouse(pixel.V(115, 85))
+		memory.Press(input.MouseButtonLeft)
+		stepWithin(t, e, 3, 0.5)
+
+		if e.Alive(crate) || !e.Alive(pebble) {
+			t.Errorf("indexed: %v: crate alive: %v, pebble alive: %v, want only the crate dug", index != nil,
+				e.Alive(crate), e.Alive(pebble))
+		}
+
+		// Diggables without any size can still be dug from close by.
+		memory.MoveMouse(pixel.V(304, 297))
+		stepWithin(t, e, 3, 0.5)
+
+		if e.Alive(pebble) {
+			t.Errorf("indexed: %v: pebble wasn't dug", index != nil)
+		}
+		e.Close()
+	}
+}
+
+func TestDigManyAtOnce(t *testing.T) {
+	e, memory := newDigWorld(NewSpatialIndex(64))
+	defer e.Close()
+
+	// Only a few entities can be added before each update.
+	var ids []ecs.EntityID
+	for i := 0; i < 120; i++ {
+		ids = append(ids, e.AddEntity(&Transform{X: 100, Y: 100, Width: 20, Height: 20}, &Diggable{0.5, 0.5},
+			&Renderable{}, &Collider{}))
+		if i%40 == 39 {
+			stepWithin(t, e, 1, 0)
+		}
+	}
+
+	// Every diggable breaks in the same update.
+	memory.MoveMouse(pixel.V(100, 100))
+	memory.Press(input.MouseButtonLeft)
+	stepWithin(t, e, 2, 0.5)
+
+	for _, id := range ids {
+		if e.Alive(id) {
+			t.Fatalf("%v wasn't dug", id)
+		}
+	}
+}
+
+func TestCollisionSystemForgetsExtents(t *testing.T) {
+	index := NewSpatialIndex(64)
+	e, _ := newDigWorld(index)
+	defer e.Close()
+
+	for i := 0; i < 10; i++ {
+		e.AddEntity(&Transform{X: float64(i) * 10}, &Diggable{})
+	}
+	wall := e.AddEntity(&Transform{}, &Collider{Width: 10, Height: 10})
+	crate := e.AddEntity(&Transform{}, &Collider{Width: 30, Height: 30})
+	stepWithin(t, e, 1, 0)
+
+	if len(index.extents.extents) != 2 || index.MaxExtent() != 15 {
+		t.Errorf("index has %d extents up to %v, want only the 2 colliders' up to 15", len(index.extents.extents),
+			index.MaxExtent())
+	}
+
+	e.RemoveEntity(wall)
+	e.RemoveComponent(crate, reflect.TypeOf(&Collider{}))
+	stepWithin(t, e, 1, 0)
+
+	if len(index.extents.extents) != 0 || index.MaxExtent() != 0 {
+		t.Errorf("index has %d extents up to %v, want none", len(index.extents.extents), index.MaxExtent())
+	}
+}
+
+func TestDigLargeDiggablesWithIndex(t *testing.T) {
+	e, memory := newDigWorld(NewSpatialIndex(16))
+	defer e.Close()
+
+	// Both are much larger than the index's cells, and the mouse is over their far corners.
+	boulder := e.AddEntity(&Transform{X: 100, Y: 100}, &Diggable{1, 1}, &Renderable{},
+		&Collider{Width: 200, Height: 200})
+	slab := e.AddEntity(&Transform{X: 600, Y: 100, Width: 300, Height: 300}, &Diggable{1, 1}, &Renderable{})
+	stepWithin(t, e, 1, 0)
+
+	memory.Press(input.MouseButtonLeft)
+	for id, mouse := range map[ecs.EntityID]pixel.Vec{boulder: pixel.V(190, 10), slab: pixel.V(740, 240)} {
+		memory.MoveMouse(mouse)
+		stepWithin(t, e, 3, 0.5)
+
+		if e.Alive(id) {
+			t.Errorf("%v wasn't dug from %v", id, mouse)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package systems
+
+import (
+	"errors"
+	"github.com/emctague/go-loopy/ecs"
+)
+
+// ChangeHUDPromptEvent represents a request to change the prompt string of a HUDLine.
+type ChangeHUDPromptEvent struct {
+	ID     ecs.EntityID
+	Prompt string
+}
+
+// TargetEntity returns the entity whose HUDLine is changing.
+func (c ChangeHUDPromptEvent) TargetEntity() ecs.EntityID {
+	return c.ID
+}
+
+// HUDSystem is a system which applies changes to the prompts of HUDLines.
+func HUDSystem(e *ecs.ECS) {
+	events := e.SubscribeTo(ChangeHUDPromptEvent{})
+
+	go e.HandleEvents("HUDSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+
+		case ChangeHUDPromptEvent:
+			line, ok := ecs.ComponentOf[HUDLine](e, event.ID)
+			if !ok {
+				e.ReportError("HUDSystem", event, errors.New("cannot change prompt on an entity with no HUDLine component"))
+				break
+			}
+
+			line.Prompt = event.Prompt
+		}
+	})
+}
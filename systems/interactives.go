@@ -2,7 +2,7 @@ package systems
 
 import (
 	"github.com/emctague/go-loopy/ecs"
-	"github.com/faiface/pixel/pixelgl"
+	"github.com/emctague/go-loopy/input"
 	"math"
 	"strconv"
 )
@@ -34,7 +34,7 @@ type Interactive struct {
 type Interactor struct {
 	InMenu            bool             // True if a menu is currently active.
 	Menu              *InteractionMenu // A pointer to the currently active menu.
-	NearbyInteractive uint64           // The ID of a nearby interactive, or 0 if nothing is in range
+	NearbyInteractive ecs.EntityID     // The ID of a nearby interactive, or 0 if nothing is in range
 }
 
 type eInteractor struct {
@@ -48,70 +48,75 @@ type eInteractive struct {
 
 type interactiveContext struct {
 	primaryLabel  *HUDLine
-	ePrimaryLabel uint64
+	ePrimaryLabel ecs.EntityID
 
 	secondaryLabel  *HUDLine
 	tSecondaryLabel *Transform
-	eSecondaryLabel uint64
+	eSecondaryLabel ecs.EntityID
 
-	interactors  map[uint64]eInteractor
-	interactives map[uint64]eInteractive
+	interactors  map[ecs.EntityID]eInteractor
+	interactives map[ecs.EntityID]eInteractive
 
 	events chan ecs.EventContainer
 
-	e   *ecs.ECS
-	win *pixelgl.Window
+	e     *ecs.ECS
+	input input.Actions
+	index *SpatialIndex
 }
 
-// InteractiveSystem handles interactive in-game menus.
-func InteractiveSystem(e *ecs.ECS, win *pixelgl.Window) {
+// interactionRange is the furthest an interactor can be from an interactive to interact with it.
+const interactionRange = 100
+
+// InteractiveSystem handles interactive in-game menus. If index isn't nil, it is used to find interactives near each
+// interactor.
+func InteractiveSystem(e *ecs.ECS, in input.Actions, index *SpatialIndex) {
 
 	var ctx = interactiveContext{
-		primaryLabel: &HUDLine{Centered: true, FontSize: 2},
+		primaryLabel: &HUDLine{Centered: true, FontSize: 2, WorldSpace: true},
 
-		secondaryLabel:  &HUDLine{Centered: true, FontSize: 1.5},
+		secondaryLabel:  &HUDLine{Centered: true, FontSize: 1.5, WorldSpace: true},
 		tSecondaryLabel: &Transform{},
 
-		interactors:  make(map[uint64]eInteractor),
-		interactives: make(map[uint64]eInteractive),
+		interactors:  make(map[ecs.EntityID]eInteractor),
+		interactives: make(map[ecs.EntityID]eInteractive),
 
-		events: e.Subscribe(),
+		events: e.SubscribeTo(ecs.SetupEvent{}, ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{},
+			ecs.ComponentRemovedEvent{}, ecs.EntityRemovedEvent{}, ecs.UpdateBeginEvent{}),
 
-		e:   e,
-		win: win,
+		e:     e,
+		input: in,
+		index: index,
 	}
 
-	go func() {
-		for ev := range ctx.events {
-			switch event := ev.Event.(type) {
-			case ecs.SetupEvent:
-				ctx.eSecondaryLabel = e.AddEntity(ctx.secondaryLabel, ctx.tSecondaryLabel)
-				ctx.ePrimaryLabel = e.AddEntity(ctx.primaryLabel, &Transform{0, 20, 0, 0, 0, ctx.eSecondaryLabel})
+	go e.HandleEvents("InteractiveSystem", ctx.events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.SetupEvent:
+			// The labels are created again whenever the system is set up, so they aren't saved.
+			ctx.eSecondaryLabel = e.AddEntity(ctx.secondaryLabel, ctx.tSecondaryLabel, &ecs.SystemOwned{})
+			ctx.ePrimaryLabel = e.AddEntity(ctx.primaryLabel, &Transform{Y: 20, ParentID: ctx.eSecondaryLabel,
+				OnParentRemoved: DestroyWithParent}, &ecs.SystemOwned{})
 
-			case ecs.EntityAddedEvent:
-				ecs.UnpackEntity(event, &ctx.interactors)
-				ecs.UnpackEntity(event, &ctx.interactives)
+		case ecs.EntityChange:
+			ecs.RefreshEntity(event, &ctx.interactors)
+			ecs.RefreshEntity(event, &ctx.interactives)
 
-			case ecs.EntityRemovedEvent:
-				ecs.RemoveEntity(event.ID, &ctx.interactors)
-				ecs.RemoveEntity(event.ID, &ctx.interactives)
+		case ecs.EntityRemovedEvent:
+			ecs.RemoveEntity(event.ID, &ctx.interactors)
+			ecs.RemoveEntity(event.ID, &ctx.interactives)
 
-			case ecs.UpdateBeginEvent:
+		case ecs.UpdateBeginEvent:
 
-				for _, interactor := range ctx.interactors {
+			for _, interactor := range ctx.interactors {
 
-					// Deal with the interactor differently if it's already in a menu.
-					if interactor.InMenu {
-						ctx.handleInteractorInMenu(ev, interactor)
-					} else {
-						ctx.handleInteractorInGame(ev, interactor)
-					}
+				// Deal with the interactor differently if it's already in a menu.
+				if interactor.InMenu {
+					ctx.handleInteractorInMenu(ev, interactor)
+				} else {
+					ctx.handleInteractorInGame(ev, interactor)
 				}
 			}
-
-			ev.Done()
 		}
-	}()
+	})
 }
 
 // handleInteractorInMenu handles user input during an interaction with an interactive.
@@ -123,8 +128,8 @@ func (ctx *interactiveContext) handleInteractorInMenu(ev ecs.EventContainer, int
 	for i, choice := range interactor.Menu.Choices {
 		choiceList += strconv.Itoa(i+1) + ") " + choice.Label + "\n"
 
-		if ctx.win.JustPressed(pixelgl.Key1+pixelgl.Button(i)) ||
-			(len(interactor.Menu.Choices) == 1 && ctx.win.JustPressed(pixelgl.KeySpace)) {
+		if ctx.input.ActionJustPressed(ActionMenuChoice(i+1)) ||
+			(len(interactor.Menu.Choices) == 1 && ctx.input.ActionJustPressed(ActionInteract)) {
 
 			if choice.Action == nil {
 				interactor.Menu = nil
@@ -152,11 +157,11 @@ func (ctx *interactiveContext) handleInteractorInGame(ev ecs.EventContainer, int
 		ev.Next <- ChangeHUDPromptEvent{ctx.ePrimaryLabel, ""}
 		ev.Next <- ChangeHUDPromptEvent{ctx.eSecondaryLabel, ""}
 	} else {
-		ev.Next <- TransformEvent{ctx.eSecondaryLabel, nearestInteractive.X, nearestInteractive.Y + 40, true}
+		ev.Next <- TransformEvent{ctx.eSecondaryLabel, nearestInteractive.World.X, nearestInteractive.World.Y + 40, true}
 		ev.Next <- ChangeHUDPromptEvent{ctx.ePrimaryLabel, nearestInteractive.Name}
 		ev.Next <- ChangeHUDPromptEvent{ctx.eSecondaryLabel, nearestInteractive.Prompt}
 
-		if ctx.win.JustPressed(pixelgl.KeySpace) {
+		if ctx.input.ActionJustPressed(ActionInteract) {
 			interactor.InMenu = true
 			interactor.NearbyInteractive = 0
 			interactor.Menu = nearestInteractive.Menu(ev)
@@ -164,15 +169,29 @@ func (ctx *interactiveContext) handleInteractorInGame(ev ecs.EventContainer, int
 	}
 }
 
-// findNearestInteractive locates an arbitrary interactive component within interaction range of the given interactor.
+// findNearestInteractive locates the nearest interactive component within interaction range of the given interactor.
 // It will return 0 if no such components are found.
-func (ctx *interactiveContext) findNearestInteractive(interactor eInteractor) (uint64, eInteractive) {
-	// Identify nearest interactive
-	for iid, interactive := range ctx.interactives {
-		if math.Hypot(interactive.X-interactor.X, interactive.Y-interactor.Y) < 100 {
-			return iid, interactive
+func (ctx *interactiveContext) findNearestInteractive(interactor eInteractor) (ecs.EntityID, eInteractive) {
+	if ctx.index != nil {
+		iid, ok := ctx.index.Nearest(interactor.World.Position(), interactionRange, func(id ecs.EntityID) bool {
+			_, isInteractive := ctx.interactives[id]
+			return isInteractive
+		})
+		if !ok {
+			return 0, eInteractive{}
+		}
+		return iid, ctx.interactives[iid]
+	}
+
+	var nearestID ecs.EntityID
+	nearestDistance := math.Inf(1)
+	for _, iid := range sortedIDs(ctx.interactives) {
+		interactive := ctx.interactives[iid]
+		distance := interactive.World.Position().To(interactor.World.Position()).Len()
+		if distance <= interactionRange && distance < nearestDistance {
+			nearestID, nearestDistance = iid, distance
 		}
 	}
 
-	return 0, eInteractive{}
+	return nearestID, ctx.interactives[nearestID]
 }
@@ -15,8 +15,8 @@ type eParticle struct {
 }
 
 // ParticleSystem deals with a very specific type of onscreen particle:
-// A circle whose size decreases over time and which obeys some gravity.
-var ParticleSystem = ecs.BehaviorSystem(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID uint64, particle eParticle) {
+// A circle whose size decreases over time. Particles fall under the gravity of their Physics, e.g. GravityY: -1500.
+var ParticleSystem = ecs.BehaviorSystemOf(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID ecs.EntityID, particle eParticle) {
 	// Track particle lifetime
 	particle.Lifetime -= delta
 	if particle.Lifetime <= 0 {
@@ -25,7 +25,4 @@ var ParticleSystem = ecs.BehaviorSystem(func(e *ecs.ECS, ev ecs.EventContainer,
 
 	// Reduce particle size
 	//particle.Radius = 5 * (particle.Lifetime / 0.25)
-
-	// Apply velocity to the particle
-	ev.Next <- ApplyVelocityEvent{entityID, 0, -1500 * delta}
 })
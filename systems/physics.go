@@ -1,63 +1,177 @@
 package systems
 
 import (
+	"errors"
 	"github.com/emctague/go-loopy/ecs"
-	"github.com/faiface/pixel/pixelgl"
-	"log"
+	"github.com/faiface/pixel"
+	"math"
 )
 
 // Physics is a component which specifies that an entity should be affected by the physics system.
 type Physics struct {
-	VelX       float64
-	VelY       float64
-	DragFactor float64
+	VelX            float64
+	VelY            float64
+	AngularVelocity float64 // How fast the entity spins, in radians per second.
+	DragFactor      float64 // The fraction of velocity and angular velocity kept over each sixtieth of a second.
+
+	Mass          float64 // How hard the entity is to push around, or 0 for 1.
+	GravityX      float64 // Gravity which pulls on this entity alone, on top of the global gravity.
+	GravityY      float64
+	IgnoreGravity bool // True if the global gravity doesn't pull on this entity.
+
+	Restitution float64 // How much of its speed the entity keeps when bouncing off solid bodies, from 0 to 1.
+	Friction    float64 // How much sliding along solid bodies slows the entity down, from 0 upwards.
+
+	forceX, forceY, torque float64 // Forces to be applied in the next update.
+}
+
+// dragReferenceRate is the number of updates per second which a DragFactor is given for. Drag is scaled to match
+// however long each update actually takes.
+const dragReferenceRate = 60
+
+// inverseMass returns one over the entity's mass, with the default mass applied.
+func (p *Physics) inverseMass() float64 {
+	if p.Mass == 0 {
+		return 1
+	}
+	return 1 / p.Mass
 }
 
 // ApplyVelocityEvent is used to add instantaneous velocity to an entity.
 type ApplyVelocityEvent struct {
-	EntityID uint64
+	EntityID ecs.EntityID
 	VelX     float64
 	VelY     float64
 }
 
-// PhysicsSystem handles object physics (velocity, etc.)
-func PhysicsSystem(e *ecs.ECS, win *pixelgl.Window) {
+// TargetEntity returns the entity being accelerated.
+func (a ApplyVelocityEvent) TargetEntity() ecs.EntityID {
+	return a.EntityID
+}
+
+// ApplyImpulseEvent is used to instantly push an entity. Unlike ApplyVelocityEvent, heavier entities are pushed less.
+type ApplyImpulseEvent struct {
+	EntityID ecs.EntityID
+	X        float64
+	Y        float64
+	Angular  float64 // An instant push to the entity's spin.
+}
+
+// TargetEntity returns the entity being pushed.
+func (a ApplyImpulseEvent) TargetEntity() ecs.EntityID {
+	return a.EntityID
+}
+
+// ApplyForceEvent is used to push an entity over time. Forces add up until the next update, which applies them over
+// the time it takes. Forces which should act continuously must be applied every update.
+type ApplyForceEvent struct {
+	EntityID ecs.EntityID
+	X        float64
+	Y        float64
+	Torque   float64 // A force which spins the entity.
+}
+
+// TargetEntity returns the entity being pushed.
+func (a ApplyForceEvent) TargetEntity() ecs.EntityID {
+	return a.EntityID
+}
+
+// SetGravityEvent changes the global gravity which pulls on every entity with Physics.
+type SetGravityEvent struct {
+	X float64
+	Y float64
+}
+
+// PhysicsStepEvent is published once the physics system has moved every body in an update. Systems which change
+// velocities should handle it rather than UpdateBeginEvent, so that they run after the physics system instead of
+// alongside it.
+type PhysicsStepEvent struct {
+	Delta float64
+}
+
+// PhysicsSystem handles object physics (velocity, forces, gravity, etc.), starting out with the given global gravity.
+func PhysicsSystem(e *ecs.ECS, gravity pixel.Vec) {
 	type ComponentSet struct {
 		*Transform
 		*Physics
 	}
-	entities := make(map[uint64]ComponentSet)
-	events := e.Subscribe()
-
-	go func() {
-		for ev := range events {
-			switch event := ev.Event.(type) {
-			case ecs.EntityAddedEvent:
-				ecs.UnpackEntity(event, &entities)
-
-			case ecs.EntityRemovedEvent:
-				ecs.RemoveEntity(event.ID, &entities)
-
-			case ApplyVelocityEvent:
-				ent, ok := entities[event.EntityID]
-				if !ok {
-					log.Fatal("Cannot apply velocity to entity without physics")
-				}
-				ent.VelX += event.VelX
-				ent.VelY += event.VelY
+	entities := make(map[ecs.EntityID]ComponentSet)
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ApplyVelocityEvent{}, ApplyImpulseEvent{}, ApplyForceEvent{}, SetGravityEvent{},
+		ecs.UpdateBeginEvent{})
+
+	// physicsOf returns the physics of the entity targeted by an event, reporting an error if it has none.
+	physicsOf := func(event ecs.Targeted) (*Physics, bool) {
+		ent, ok := entities[event.TargetEntity()]
+		if !ok {
+			e.ReportError("PhysicsSystem", event, errors.New("cannot apply physics to entity without physics"))
+			return nil, false
+		}
+		return ent.Physics, true
+	}
+
+	go e.HandleEvents("PhysicsSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			ecs.RefreshEntity(event, &entities)
 
-			case ecs.UpdateBeginEvent:
-				for eid, entity := range entities {
+		case ecs.EntityRemovedEvent:
+			ecs.RemoveEntity(event.ID, &entities)
 
-					entity.VelX *= entity.DragFactor
-					entity.VelY *= entity.DragFactor
+		case ApplyVelocityEvent:
+			if physics, ok := physicsOf(event); ok {
+				physics.VelX += event.VelX
+				physics.VelY += event.VelY
+			}
+
+		case ApplyImpulseEvent:
+			if physics, ok := physicsOf(event); ok {
+				physics.VelX += event.X * physics.inverseMass()
+				physics.VelY += event.Y * physics.inverseMass()
+				physics.AngularVelocity += event.Angular * physics.inverseMass()
+			}
 
-					ev.Next <- TransformEvent{eid, entity.VelX * event.Delta, entity.VelY * event.Delta, false}
+		case ApplyForceEvent:
+			if physics, ok := physicsOf(event); ok {
+				physics.forceX += event.X
+				physics.forceY += event.Y
+				physics.torque += event.Torque
+			}
+
+		case SetGravityEvent:
+			gravity = pixel.V(event.X, event.Y)
+
+		case ecs.UpdateBeginEvent:
+			// Every body may move, so the moves are published together, followed by the end of the step.
+			var moves ecs.EventBatch
+
+			for _, eid := range sortedIDs(entities) {
+				entity := entities[eid]
+
+				acceleration := pixel.V(entity.GravityX, entity.GravityY)
+				if !entity.IgnoreGravity {
+					acceleration = acceleration.Add(gravity)
 				}
+				acceleration = acceleration.Add(pixel.V(entity.forceX, entity.forceY).Scaled(entity.inverseMass()))
 
+				entity.VelX += acceleration.X * event.Delta
+				entity.VelY += acceleration.Y * event.Delta
+				entity.AngularVelocity += entity.torque * entity.inverseMass() * event.Delta
+				entity.forceX, entity.forceY, entity.torque = 0, 0, 0
+
+				drag := math.Pow(entity.DragFactor, event.Delta*dragReferenceRate)
+				entity.VelX *= drag
+				entity.VelY *= drag
+				entity.AngularVelocity *= drag
+
+				moves = append(moves, TransformEvent{eid, entity.VelX * event.Delta, entity.VelY * event.Delta, false})
+				if entity.AngularVelocity != 0 {
+					moves = append(moves, RotateEvent{eid, entity.AngularVelocity * event.Delta})
+				}
 			}
 
-			ev.Wg.Done()
+			ev.Next <- append(moves, PhysicsStepEvent{event.Delta})
+
 		}
-	}()
+	})
 }
@@ -0,0 +1,156 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"testing"
+)
+
+// newPhysicsWorld returns an ECS running the transform, physics and collision systems.
+func newPhysicsWorld(gravity pixel.Vec) *ecs.ECS {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	TransformSystem(&e, nil)
+	PhysicsSystem(&e, gravity)
+	CollisionSystem(&e, nil)
+	return &e
+}
+
+func TestPhysicsDragIsFrameRateIndependent(t *testing.T) {
+	var speeds []float64
+	for _, updates := range []int{30, 60, 240} {
+		e := newPhysicsWorld(pixel.ZV)
+		physics := &Physics{VelX: 100, DragFactor: 0.9}
+		e.AddEntity(&Transform{}, physics)
+
+		// Entities are added in the first update, so it takes no time.
+		stepWithin(t, e, 1, 0)
+		stepWithin(t, e, updates, 1/float64(updates))
+		speeds = append(speeds, physics.VelX)
+		e.Close()
+	}
+
+	want := 100 * math.Pow(0.9, 60)
+	for _, speed := range speeds {
+		if math.Abs(speed-want) > 1e-9 {
+			t.Errorf("got speeds %v after a second, want %v", speeds, want)
+			break
+		}
+	}
+}
+
+func TestPhysicsGravity(t *testing.T) {
+	e := newPhysicsWorld(pixel.V(0, -100))
+	defer e.Close()
+
+	falling := &Physics{DragFactor: 1}
+	floating := &Physics{DragFactor: 1, IgnoreGravity: true, GravityX: 10}
+	e.AddEntity(&Transform{}, falling)
+	e.AddEntity(&Transform{}, floating)
+
+	stepWithin(t, e, 1, 0)
+	stepWithin(t, e, 60, 1.0/60)
+
+	if math.Abs(falling.VelY+100) > 1e-9 || falling.VelX != 0 {
+		t.Errorf("falling body has velocity (%v, %v), want (0, -100)", falling.VelX, falling.VelY)
+	}
+	if math.Abs(floating.VelX-10) > 1e-9 || floating.VelY != 0 {
+		t.Errorf("floating body has velocity (%v, %v), want (10, 0)", floating.VelX, floating.VelY)
+	}
+}
+
+func TestPhysicsForcesAndImpulses(t *testing.T) {
+	e := newPhysicsWorld(pixel.ZV)
+	defer e.Close()
+
+	physics := &Physics{DragFactor: 1, Mass: 2}
+	id := e.AddEntity(&Transform{}, physics)
+	stepWithin(t, e, 1, 1.0/60)
+
+	e.PublishNextFrame(ApplyImpulseEvent{id, 200, 0, 4})
+	e.PublishNextFrame(ApplyForceEvent{id, 0, 120, 0})
+	stepWithin(t, e, 2, 1.0/60)
+
+	if physics.VelX != 100 || math.Abs(physics.VelY-1) > 1e-9 || physics.AngularVelocity != 2 {
+		t.Errorf("got velocity (%v, %v) and spin %v, want (100, 1) and 2", physics.VelX, physics.VelY,
+			physics.AngularVelocity)
+	}
+}
+
+func TestPhysicsManySpinningBodies(t *testing.T) {
+	e := newPhysicsWorld(pixel.ZV)
+	defer e.Close()
+
+	// Each body moves and turns, making 80 events, more than Next can hold. Only 50 entities can be added at once.
+	var transforms []*Transform
+	for i := 0; i < 40; i++ {
+		transform := &Transform{X: float64(i) * 100}
+		transforms = append(transforms, transform)
+		e.AddEntity(transform, &Physics{VelY: 60, AngularVelocity: 60, DragFactor: 1})
+	}
+
+	stepWithin(t, e, 1, 0)
+	stepWithin(t, e, 2, 1.0/60)
+
+	for _, transform := range transforms {
+		if math.Abs(transform.Rotation-2) > 1e-9 || math.Abs(transform.Y-2) > 1e-9 {
+			t.Fatalf("body ended up at y = %v, turned by %v, want 2 and 2", transform.Y, transform.Rotation)
+		}
+	}
+}
+
+// dropBall drops a ball onto a fixed floor for the given number of updates.
+func dropBall(t *testing.T, ball *Physics, updates int) {
+	e := newPhysicsWorld(pixel.V(0, -1000))
+	defer e.Close()
+
+	e.AddEntity(&Transform{Y: 100}, ball, &Collider{Shape: ShapeCircle, Radius: 5})
+	e.AddEntity(&Transform{Width: 1000, Height: 20}, &Collider{})
+
+	stepWithin(t, e, updates, 1.0/60)
+}
+
+func TestPhysicsRestitution(t *testing.T) {
+	for _, restitution := range []float64{0, 0.5, 1} {
+		ball := &Physics{DragFactor: 1, Restitution: restitution}
+		e := newPhysicsWorld(pixel.V(0, -1000))
+
+		transform := &Transform{Y: 100}
+		e.AddEntity(transform, ball, &Collider{Shape: ShapeCircle, Radius: 5})
+		e.AddEntity(&Transform{Width: 1000, Height: 20}, &Collider{})
+
+		// Find the fastest the ball falls, and the fastest it bounces back up.
+		var fallSpeed, bounceSpeed float64
+		for i := 0; i < 60; i++ {
+			stepWithin(t, e, 1, 1.0/60)
+			fallSpeed = math.Max(fallSpeed, -ball.VelY)
+			bounceSpeed = math.Max(bounceSpeed, ball.VelY)
+		}
+		e.Close()
+
+		if transform.Y < 15-contactSlop {
+			t.Errorf("restitution %v: ball fell through the floor to %v", restitution, transform.Y)
+		}
+
+		// Gravity takes away up to one update's worth of speed before the bounce is seen.
+		if want := fallSpeed * restitution; math.Abs(bounceSpeed-want) > 1000.0/60+1e-9 {
+			t.Errorf("restitution %v: bounced at %v after falling at %v, want about %v", restitution, bounceSpeed,
+				fallSpeed, want)
+		}
+	}
+}
+
+func TestPhysicsFriction(t *testing.T) {
+	slippery := &Physics{VelX: 100, DragFactor: 1}
+	dropBall(t, slippery, 60)
+	if slippery.VelX != 100 {
+		t.Errorf("ball without friction slowed down to %v", slippery.VelX)
+	}
+
+	rough := &Physics{VelX: 100, DragFactor: 1, Friction: 1}
+	dropBall(t, rough, 60)
+	if rough.VelX != 0 {
+		t.Errorf("ball with friction is still sliding at %v", rough.VelX)
+	}
+}
@@ -2,8 +2,7 @@ package systems
 
 import (
 	"github.com/emctague/go-loopy/ecs"
-	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
+	"github.com/emctague/go-loopy/input"
 	"math"
 )
 
@@ -17,36 +16,38 @@ type ePlayer struct {
 	*Interactor
 }
 
-// PlayerSystem is a system which handles basic player controls
-var PlayerSystem = func(e *ecs.ECS, win *pixelgl.Window, pic *pixel.Picture) {
-	ecs.BehaviorSystem(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID uint64, player ePlayer) {
+// PlayerSystem is a system which handles basic player controls. Each bullet fired by the player is given a copy of the
+// bullet Renderable, with its own sprite.
+var PlayerSystem = func(e *ecs.ECS, in input.Actions, bullet Renderable) {
+	ecs.BehaviorSystemOf(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID ecs.EntityID, player ePlayer) {
 		// Don't deal with movement in menus.
 		if player.Menu != nil {
 			return
 		}
 
-		mousePos := win.MousePosition()
-		playerPos := pixel.V(player.X, player.Y)
+		mousePos := MouseWorldPosition(e, in)
+		playerPos := player.World.Position()
 		diff := mousePos.To(playerPos).Unit().Rotated(math.Pi)
 		player.Rotation = diff.Angle() - math.Pi/2
 
-		// Apply velocity related to held arrow keys.
+		// Apply velocity related to held movement keys.
 		var velX, velY float64
-		if win.Pressed(pixelgl.KeyUp) {
+		if in.ActionPressed(ActionMoveUp) {
 			velY += 800 * delta
 		}
-		if win.Pressed(pixelgl.KeyDown) {
+		if in.ActionPressed(ActionMoveDown) {
 			velY -= 800 * delta
 		}
-		if win.Pressed(pixelgl.KeyLeft) {
+		if in.ActionPressed(ActionMoveLeft) {
 			velX -= 800 * delta
 		}
-		if win.Pressed(pixelgl.KeyRight) {
+		if in.ActionPressed(ActionMoveRight) {
 			velX += 800 * delta
 		}
 
-		if win.JustPressed(pixelgl.MouseButtonLeft) {
-			e.AddEntity(&Transform{X: player.X, Y: player.Y, Rotation: player.Rotation}, &Physics{VelX: diff.X * 200, VelY: diff.Y * 200, DragFactor: 1}, &Renderable{Sprite: pixel.NewSprite(*pic, pixel.R(69, 28, 69+8, 28+8))}, &Projectile{}, &Bullet{})
+		if in.ActionJustPressed(ActionFire) {
+			e.AddEntity(&Transform{X: player.World.X, Y: player.World.Y, Rotation: player.World.Rotation}, &Physics{VelX: diff.X * 200, VelY: diff.Y * 200, DragFactor: 1}, bullet.Copy(), &Projectile{}, &Bullet{},
+				&Collider{Shape: ShapeCircle, Radius: 4, Layer: CollisionLayerBullet, Mask: CollisionLayerEnemy, Trigger: true})
 		}
 
 		// Store the new velocity.
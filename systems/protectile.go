@@ -2,8 +2,8 @@ package systems
 
 import (
 	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 	"math"
 )
 
@@ -19,37 +19,103 @@ type eProjectile struct {
 	*Projectile
 }
 
-// ProjectileSystem handles projectile movement and rotation
-var ProjectileSystem = func(e *ecs.ECS, win *pixelgl.Window) {
-	ecs.BehaviorSystem(func(e *ecs.ECS, ev ecs.EventContainer, delta float64, entityID uint64, projectile eProjectile) {
-		if projectile.Y+projectile.VelY*delta < 20 {
-			projectile.VelY = -projectile.VelY * 0.5
-			projectile.Y = 20
-			projectile.Bounces++
-		}
+// ProjectileSystem handles projectile movement and rotation. Projectiles with solid colliders also bounce off other
+// solid colliders, which are found by sweeping the projectile along its path so that it can't pass through them. The
+// spatial index, if given, is used to find colliders near each projectile's path. Projectiles are updated once the
+// physics system has moved them, in order of ID, so that their bounces are the same every time.
+var ProjectileSystem = func(e *ecs.ECS, in input.Input, index *SpatialIndex) {
+	projectiles := make(map[ecs.EntityID]eProjectile)
 
-		if projectile.Y+projectile.VelY*delta > win.Bounds().Max.Y-20 {
-			projectile.VelY = -projectile.VelY
-			projectile.Y = win.Bounds().Max.Y - 20
-			projectile.Bounces++
-		}
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, PhysicsStepEvent{})
 
-		if projectile.X+projectile.VelX*delta > win.Bounds().Max.X-20 {
-			projectile.VelX = -projectile.VelX
-			projectile.X = win.Bounds().Max.X - 20
-			projectile.Bounces++
-		}
+	go e.HandleEvents("ProjectileSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			ecs.RefreshEntityOf(event, projectiles)
 
-		if projectile.X+projectile.VelX*delta < 20 {
-			projectile.VelX = -projectile.VelX
-			projectile.X = 20
-			projectile.Bounces++
+		case ecs.EntityRemovedEvent:
+			delete(projectiles, event.ID)
+
+		case PhysicsStepEvent:
+			for _, id := range sortedIDs(projectiles) {
+				updateProjectile(e, in, index, id, projectiles[id], event.Delta)
+			}
 		}
+	})
+}
 
-		projectile.Rotation = pixel.V(projectile.VelX, projectile.VelY).Angle() + math.Pi/2
+// updateProjectile bounces a projectile off colliders and the edges of the view, removing it once it has bounced too
+// many times.
+func updateProjectile(e *ecs.ECS, in input.Input, index *SpatialIndex, entityID ecs.EntityID, projectile eProjectile,
+	delta float64) {
 
-		if projectile.Bounces > 5 {
-			e.RemoveEntity(entityID)
-		}
-	})(e)
+	if collider, ok := ecs.ComponentOf[Collider](e, entityID); ok && !collider.Trigger {
+		bounceOffColliders(e, index, entityID, projectile, collider)
+	}
+
+	// Projectiles bounce off the edges of the visible part of the world.
+	view := ViewBounds(e, in.Bounds())
+
+	if projectile.Y+projectile.VelY*delta < view.Min.Y+20 {
+		projectile.VelY = -projectile.VelY * 0.5
+		projectile.Y = view.Min.Y + 20
+		projectile.Bounces++
+	}
+
+	if projectile.Y+projectile.VelY*delta > view.Max.Y-20 {
+		projectile.VelY = -projectile.VelY
+		projectile.Y = view.Max.Y - 20
+		projectile.Bounces++
+	}
+
+	if projectile.X+projectile.VelX*delta > view.Max.X-20 {
+		projectile.VelX = -projectile.VelX
+		projectile.X = view.Max.X - 20
+		projectile.Bounces++
+	}
+
+	if projectile.X+projectile.VelX*delta < view.Min.X+20 {
+		projectile.VelX = -projectile.VelX
+		projectile.X = view.Min.X + 20
+		projectile.Bounces++
+	}
+
+	projectile.Rotation = pixel.V(projectile.VelX, projectile.VelY).Angle() + math.Pi/2
+
+	if projectile.Bounces > 5 {
+		e.RemoveEntity(entityID)
+	}
+}
+
+// bounceOffColliders reflects the projectile's velocity off the first solid collider it hit on its way through the
+// world in this update, moving it back to where they touched.
+func bounceOffColliders(e *ecs.ECS, index *SpatialIndex, entityID ecs.EntityID, projectile eProjectile,
+	collider *Collider) {
+
+	hit, ok := ShapeCast(e, index, collider, projectile.Transform, projectile.lastPosition(),
+		projectile.World.Position(), func(id ecs.EntityID) bool {
+			other, _ := ecs.ComponentOf[Collider](e, id)
+			return id != entityID && !other.Trigger
+		})
+	if !ok {
+		return
+	}
+
+	// The velocity is relative to the projectile's parent, while the hit is in the world.
+	parent := parentWorldOf(e, projectile.Transform).Matrix()
+	velocity := parent.Project(pixel.V(projectile.VelX, projectile.VelY)).Sub(parent.Project(pixel.ZV))
+
+	// Projectiles already touching a collider may be moving away from it.
+	if velocity.Dot(hit.Normal) >= 0 {
+		return
+	}
+
+	velocity = velocity.Sub(hit.Normal.Scaled(2 * velocity.Dot(hit.Normal)))
+	velocity = parent.Unproject(velocity).Sub(parent.Unproject(pixel.ZV))
+	position := parent.Unproject(hit.Point)
+
+	projectile.VelX, projectile.VelY = velocity.X, velocity.Y
+	projectile.X, projectile.Y = position.X, position.Y
+	projectile.Bounces++
 }
@@ -0,0 +1,51 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"math"
+	"testing"
+)
+
+func TestParentedProjectileBounces(t *testing.T) {
+	// Each projectile starts out at (400, 300) in the world moving right, and reaches the wall in the second update.
+	tests := []struct {
+		name       string
+		parent     Transform
+		start, hit pixel.Vec // Where the projectile starts and hits the wall, relative to its parent.
+		velX       float64
+	}{
+		{"moved", Transform{X: 300, Y: 100}, pixel.V(100, 200), pixel.V(185, 200), 300},
+		{"turned", Transform{X: 900, Y: 700, Rotation: math.Pi}, pixel.V(500, 400), pixel.V(415, 400), -300},
+	}
+
+	for _, test := range tests {
+		e := ecs.NewECS()
+		e.ErrorPolicy = ecs.ErrorPolicyCollect
+		TransformSystem(&e, nil)
+		PhysicsSystem(&e, pixel.ZV)
+		ProjectileSystem(&e, input.NewMemory(pixel.R(0, 0, 1024, 768)), nil)
+
+		parent := e.AddEntity(&test.parent)
+		e.AddEntity(&Transform{X: 500, Y: 300}, &Collider{Width: 20, Height: 200})
+		stepWithin(t, &e, 1, 0)
+
+		transform := &Transform{X: test.start.X, Y: test.start.Y, ParentID: parent}
+		physics := &Physics{VelX: test.velX, DragFactor: 1, IgnoreGravity: true}
+		e.AddEntity(transform, physics, &Projectile{}, &Collider{Width: 10, Height: 10})
+		stepWithin(t, &e, 2, 0.25)
+
+		if physics.VelX != test.velX {
+			t.Errorf("%s: bounced early, at %v in the world", test.name, transform.World.Position())
+		}
+
+		stepWithin(t, &e, 1, 0.25)
+
+		at := pixel.V(transform.X, transform.Y)
+		if math.Abs(physics.VelX+test.velX) > 1e-9 || at.Sub(test.hit).Len() > 1e-9 {
+			t.Errorf("%s: moving at %v from %v, want %v from %v", test.name, physics.VelX, at, -test.velX, test.hit)
+		}
+		e.Close()
+	}
+}
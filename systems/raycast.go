@@ -0,0 +1,208 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+)
+
+// RaycastHit describes the first collider touched by a ray, segment or swept shape.
+type RaycastHit struct {
+	ID       ecs.EntityID
+	Point    pixel.Vec // Where the ray, or the swept transform's position, was when it touched the collider.
+	Normal   pixel.Vec // The direction pointing out of the collider's surface, back towards the cast.
+	Distance float64   // How far the cast travelled before touching the collider.
+}
+
+// Raycast returns the first collider hit by a ray starting at origin and travelling up to maxDistance in the given
+// direction, which may be infinite. Only colliders on one of the layers in mask are hit, or colliders on any layer if
+// mask is zero. A nil filter accepts every entity. If an index kept up to date by the transform and collision systems
+// is given, only colliders near the ray are checked, rather than every collider in the world.
+func Raycast(e *ecs.ECS, index *SpatialIndex, origin pixel.Vec, direction pixel.Vec, maxDistance float64,
+	mask uint32, filter func(ecs.EntityID) bool) (RaycastHit, bool) {
+
+	return cast(e, index, origin, direction.Unit(), maxDistance, pixel.ZV, 0, filter, func(other *Collider) bool {
+		layer, _ := other.layers()
+		return mask == 0 || layer&mask != 0
+	})
+}
+
+// SegmentCast returns the first collider hit by the line from one point to another, in the same way as Raycast.
+func SegmentCast(e *ecs.ECS, index *SpatialIndex, from pixel.Vec, to pixel.Vec, mask uint32, filter func(ecs.EntityID) bool) (RaycastHit, bool) {
+	return Raycast(e, index, from, from.To(to), from.To(to).Len(), mask, filter)
+}
+
+// ShapeCast returns the first collider hit by the given collider as its transform moves in a straight line from one
+// position to another, so that fast-moving colliders can't pass through others between updates. The collider's layer
+// and mask pick which colliders can be hit, as they do in the collision system, and the hit's point is the position of
+// the transform when they first touch. If the collider already overlaps another at the start, it is hit at a distance of
+// zero. A nil filter accepts every entity, but the filter should usually reject the collider's own entity.
+func ShapeCast(e *ecs.ECS, index *SpatialIndex, collider *Collider, transform *Transform, from pixel.Vec, to pixel.Vec,
+	filter func(ecs.EntityID) bool) (RaycastHit, bool) {
+
+	offset := pixel.V(collider.OffsetX, collider.OffsetY)
+	half, radius := roundedShape(collider, transform)
+
+	hit, ok := cast(e, index, from.Add(offset), from.To(to).Unit(), from.To(to).Len(), half, radius, filter,
+		collider.collidesWith)
+	hit.Point = hit.Point.Sub(offset)
+	return hit, ok
+}
+
+// LineOfSight returns true if no solid collider lies on the line between two points. Colliders are only considered in
+// the same way as SegmentCast, and trigger colliders never block sight.
+func LineOfSight(e *ecs.ECS, index *SpatialIndex, from pixel.Vec, to pixel.Vec, mask uint32, filter func(ecs.EntityID) bool) bool {
+	_, blocked := SegmentCast(e, index, from, to, mask, func(id ecs.EntityID) bool {
+		if collider, ok := ecs.ComponentOf[Collider](e, id); !ok || collider.Trigger {
+			return false
+		}
+		return filter == nil || filter(id)
+	})
+	return !blocked
+}
+
+// cast sweeps a rounded box with the given half-size and corner radius from origin along a unit direction, returning
+// the first collider it touches within maxDistance which passes both filter and accepts. With an index, only colliders
+// positioned near the swept path are considered; without one, or if the path is infinite, every collider is.
+func cast(e *ecs.ECS, index *SpatialIndex, origin pixel.Vec, direction pixel.Vec, maxDistance float64, half pixel.Vec,
+	radius float64, filter func(ecs.EntityID) bool, accepts func(*Collider) bool) (hit RaycastHit, found bool) {
+
+	// Ties go to the lowest ID, whatever order the candidates are visited in.
+	consider := func(id ecs.EntityID, transform *Transform, collider *Collider) {
+		if (filter != nil && !filter(id)) || !accepts(collider) {
+			return
+		}
+
+		// Sweeping one rounded box against another is the same as casting a ray against their sum.
+		otherHalf, otherRadius := roundedShape(collider, transform)
+		distance, normal, ok := raycastRoundedBox(origin, direction, maxDistance, collider.Center(transform),
+			half.Add(otherHalf), radius+otherRadius)
+
+		if ok && (!found || distance < hit.Distance || (distance == hit.Distance && id < hit.ID)) {
+			hit = RaycastHit{id, origin.Add(direction.Scaled(distance)), normal, distance}
+			found = true
+		}
+	}
+
+	if index == nil || math.IsInf(maxDistance, 0) {
+		ecs.Each2(e, consider)
+		return
+	}
+
+	// A collider can only be touched if its position is within reach of the path, widened by the swept shape's own
+	// size and the size of the largest collider.
+	end := origin.Add(direction.Scaled(maxDistance))
+	margin := math.Max(half.X, half.Y) + radius + index.MaxExtent()
+	bounds := pixel.R(math.Min(origin.X, end.X)-margin, math.Min(origin.Y, end.Y)-margin,
+		math.Max(origin.X, end.X)+margin, math.Max(origin.Y, end.Y)+margin)
+
+	for _, id := range index.QueryRect(bounds) {
+		transform, hasTransform := ecs.ComponentOf[Transform](e, id)
+		collider, hasCollider := ecs.ComponentOf[Collider](e, id)
+		if hasTransform && hasCollider {
+			consider(id, transform, collider)
+		}
+	}
+
+	return
+}
+
+// roundedShape describes a collider as a box with rounded corners: AABBs have no corner radius, and circles are boxes
+// of no size.
+func roundedShape(collider *Collider, transform *Transform) (half pixel.Vec, radius float64) {
+	if collider.Shape == ShapeCircle {
+		return pixel.ZV, collider.Radius
+	}
+	return collider.Size(transform).Scaled(0.5), 0
+}
+
+// raycastRoundedBox returns how far a ray from origin in a unit direction travels before touching a box centered on
+// center, whose corners are rounded with the given radius, and the normal of the surface it touches. A zero direction
+// only checks whether the origin is inside the box. Rays starting inside the box touch it at a distance of zero.
+func raycastRoundedBox(origin pixel.Vec, direction pixel.Vec, maxDistance float64, center pixel.Vec, half pixel.Vec,
+	radius float64) (distance float64, normal pixel.Vec, ok bool) {
+
+	local := center.To(origin)
+	closest := pixel.V(math.Max(-half.X, math.Min(local.X, half.X)), math.Max(-half.Y, math.Min(local.Y, half.Y)))
+	if closest.To(local).Len() <= radius+contactSlop {
+		return 0, insideNormal(local, closest, half), true
+	}
+
+	// Find where the ray enters the box grown by the radius, using the slab method.
+	outer := half.Add(pixel.V(radius, radius))
+	entry, exit := 0.0, maxDistance
+	for axis := 0; axis < 2; axis++ {
+		position, step, extent := local.X, direction.X, outer.X
+		if axis == 1 {
+			position, step, extent = local.Y, direction.Y, outer.Y
+		}
+
+		if step == 0 {
+			if math.Abs(position) > extent {
+				return 0, pixel.ZV, false
+			}
+			continue
+		}
+
+		near, far := (-extent-position)/step, (extent-position)/step
+		if near > far {
+			near, far = far, near
+		}
+		if near > entry {
+			entry = near
+			normal = pixel.V(0, -sign(step))
+			if axis == 0 {
+				normal = pixel.V(-sign(step), 0)
+			}
+		}
+		exit = math.Min(exit, far)
+	}
+
+	if entry > exit {
+		return 0, pixel.ZV, false
+	}
+
+	// Entering the grown box beside one of the box's edges means the ray hit that flat edge. Otherwise it is beside a
+	// corner, where the surface is rounded.
+	point := local.Add(direction.Scaled(entry))
+	if radius == 0 || math.Abs(point.X) <= half.X || math.Abs(point.Y) <= half.Y {
+		return entry, normal, true
+	}
+
+	corner := pixel.V(sign(point.X)*half.X, sign(point.Y)*half.Y)
+	return raycastCircle(local, direction, maxDistance, corner, radius)
+}
+
+// raycastCircle returns how far a ray from origin, in a unit direction, travels before touching the edge of a circle,
+// and the normal of the circle there. The ray must start outside the circle.
+func raycastCircle(origin pixel.Vec, direction pixel.Vec, maxDistance float64, center pixel.Vec,
+	radius float64) (distance float64, normal pixel.Vec, ok bool) {
+
+	offset := center.To(origin)
+	b := offset.Dot(direction)
+	c := offset.Dot(offset) - radius*radius
+	discriminant := b*b - c
+	if discriminant < 0 {
+		return 0, pixel.ZV, false
+	}
+
+	distance = -b - math.Sqrt(discriminant)
+	if distance < 0 || distance > maxDistance {
+		return 0, pixel.ZV, false
+	}
+
+	return distance, offset.Add(direction.Scaled(distance)).Unit(), true
+}
+
+// insideNormal returns the normal for a ray starting inside a rounded box, relative to the box's center: the way out
+// of the box from the nearest point on its inner edges, or along the shallowest axis if the ray starts within them.
+func insideNormal(local pixel.Vec, closest pixel.Vec, half pixel.Vec) pixel.Vec {
+	if local != closest {
+		return closest.To(local).Unit()
+	}
+
+	if half.X-math.Abs(local.X) < half.Y-math.Abs(local.Y) {
+		return pixel.V(sign(local.X), 0)
+	}
+	return pixel.V(0, sign(local.Y))
+}
@@ -0,0 +1,128 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"testing"
+)
+
+// newCastWorld returns an ECS running the transform and collision systems, sharing a spatial index.
+func newCastWorld() (*ecs.ECS, *SpatialIndex) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	index := NewSpatialIndex(64)
+	TransformSystem(&e, index)
+	CollisionSystem(&e, index)
+	return &e, index
+}
+
+// eachIndex runs the test both without and with the spatial index, which should give the same results.
+func eachIndex(t *testing.T, index *SpatialIndex, test func(t *testing.T, index *SpatialIndex)) {
+	t.Run("unindexed", func(t *testing.T) { test(t, nil) })
+	t.Run("indexed", func(t *testing.T) { test(t, index) })
+}
+
+func TestRaycastHit(t *testing.T) {
+	e, index := newCastWorld()
+	defer e.Close()
+
+	box := e.AddEntity(&Transform{X: 100, Width: 20, Height: 20}, &Collider{})
+	stepWithin(t, e, 1, 0)
+
+	eachIndex(t, index, func(t *testing.T, index *SpatialIndex) {
+		hit, ok := Raycast(e, index, pixel.ZV, pixel.V(1, 0), 1000, 0, nil)
+		if !ok || hit.ID != box {
+			t.Fatalf("got hit %+v, %v, want a hit on %v", hit, ok, box)
+		}
+		if math.Abs(hit.Distance-90) > 1e-9 || hit.Point.To(pixel.V(90, 0)).Len() > 1e-9 || hit.Normal != pixel.V(-1, 0) {
+			t.Errorf("got hit %+v, want distance 90 at (90, 0) facing left", hit)
+		}
+	})
+}
+
+func TestRaycastMiss(t *testing.T) {
+	e, index := newCastWorld()
+	defer e.Close()
+
+	e.AddEntity(&Transform{X: 100, Width: 20, Height: 20}, &Collider{Layer: 2})
+	stepWithin(t, e, 1, 0)
+
+	eachIndex(t, index, func(t *testing.T, index *SpatialIndex) {
+		if hit, ok := Raycast(e, index, pixel.ZV, pixel.V(0, 1), 1000, 0, nil); ok {
+			t.Errorf("ray pointing away hit %+v", hit)
+		}
+		if hit, ok := Raycast(e, index, pixel.ZV, pixel.V(1, 0), 50, 0, nil); ok {
+			t.Errorf("short ray hit %+v", hit)
+		}
+		if hit, ok := Raycast(e, index, pixel.ZV, pixel.V(1, 0), 1000, 1, nil); ok {
+			t.Errorf("ray masking out the box's layer hit %+v", hit)
+		}
+		if hit, ok := Raycast(e, index, pixel.ZV, pixel.V(1, 0), 1000, 0, func(ecs.EntityID) bool { return false }); ok {
+			t.Errorf("filtered ray hit %+v", hit)
+		}
+		if !LineOfSight(e, index, pixel.ZV, pixel.V(50, 0), 0, nil) {
+			t.Error("line of sight short of the box was blocked")
+		}
+	})
+}
+
+func TestRaycastTiesGoToLowestID(t *testing.T) {
+	e, index := newCastWorld()
+	defer e.Close()
+
+	first := e.AddEntity(&Transform{X: 100, Y: 5}, &Collider{Shape: ShapeCircle, Radius: 10})
+	e.AddEntity(&Transform{X: 100, Y: -5}, &Collider{Shape: ShapeCircle, Radius: 10})
+	stepWithin(t, e, 1, 0)
+
+	eachIndex(t, index, func(t *testing.T, index *SpatialIndex) {
+		if hit, ok := Raycast(e, index, pixel.ZV, pixel.V(1, 0), 1000, 0, nil); !ok || hit.ID != first {
+			t.Errorf("got hit %+v, %v, want a hit on %v", hit, ok, first)
+		}
+	})
+}
+
+func TestRaycastLargeColliderFarFromPath(t *testing.T) {
+	e, index := newCastWorld()
+	defer e.Close()
+
+	// The wall's position is far from the ray, but the wall reaches across it.
+	wall := e.AddEntity(&Transform{X: 100, Y: 300, Width: 20, Height: 600}, &Collider{})
+	stepWithin(t, e, 1, 0)
+
+	eachIndex(t, index, func(t *testing.T, index *SpatialIndex) {
+		if hit, ok := Raycast(e, index, pixel.V(0, 10), pixel.V(1, 0), 200, 0, nil); !ok || hit.ID != wall {
+			t.Errorf("got hit %+v, %v, want a hit on %v", hit, ok, wall)
+		}
+	})
+}
+
+func TestShapeCastTimeOfImpact(t *testing.T) {
+	e, index := newCastWorld()
+	defer e.Close()
+
+	box := e.AddEntity(&Transform{X: 100, Width: 20, Height: 20}, &Collider{})
+	stepWithin(t, e, 1, 0)
+
+	eachIndex(t, index, func(t *testing.T, index *SpatialIndex) {
+		// The circle's center is offset ahead of the transform, so the transform stops short of where it touches.
+		collider := &Collider{Shape: ShapeCircle, Radius: 5, OffsetX: 3}
+		hit, ok := ShapeCast(e, index, collider, &Transform{}, pixel.ZV, pixel.V(200, 0), nil)
+		if !ok || hit.ID != box {
+			t.Fatalf("got hit %+v, %v, want a hit on %v", hit, ok, box)
+		}
+		if math.Abs(hit.Distance-82) > 1e-9 || hit.Point.To(pixel.V(82, 0)).Len() > 1e-9 {
+			t.Errorf("got hit %+v, want the transform to touch the box at (82, 0)", hit)
+		}
+
+		// Overlapping colliders are hit straight away.
+		hit, ok = ShapeCast(e, index, collider, &Transform{}, pixel.V(95, 0), pixel.V(200, 0), nil)
+		if !ok || hit.Distance != 0 {
+			t.Errorf("got hit %+v, %v, want a hit at distance 0", hit, ok)
+		}
+
+		if hit, ok := ShapeCast(e, index, collider, &Transform{}, pixel.V(0, 50), pixel.V(200, 50), nil); ok {
+			t.Errorf("cast passing above the box hit %+v", hit)
+		}
+	})
+}
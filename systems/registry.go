@@ -0,0 +1,139 @@
+package systems
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+)
+
+// savedRenderable is the serialized form of a Renderable, which stores the sprite's name within the atlas, or its frame
+// within the atlas's picture if it has no name. Renderables without a sprite have neither.
+type savedRenderable struct {
+	Name   string
+	Frame  *pixel.Rect
+	Layer  Layer
+	ZIndex int
+}
+
+// savedAnimation is the serialized form of an Animation, whose clips are taken from the atlas when loading.
+type savedAnimation struct {
+	Playing  string
+	Frame    int
+	Elapsed  float64
+	Reverse  bool
+	Finished bool
+}
+
+// savedInteractive is the serialized form of an Interactive. Its menu is looked up by name when loading.
+type savedInteractive struct {
+	Prompt string
+	Name   string
+}
+
+// ComponentRegistry returns a registry of the components defined by this package, for saving and loading games.
+// Sprites can't be serialized, so renderables are saved as a sprite name or frame within the given atlas, and
+// animations always play the atlas's clips. Interactive menus
+// are functions, so they are looked up by the interactive's name in the given map when loading.
+// Interactors are always loaded outside of any menu, and HUDLines are not saved, as they belong to their systems.
+func ComponentRegistry(atlas *Atlas, menus map[string]func(ecs.EventContainer) *InteractionMenu) *ecs.ComponentRegistry {
+	registry := ecs.NewComponentRegistry()
+
+	registry.Register("Transform", &Transform{})
+	registry.Register("Physics", &Physics{})
+	registry.Register("Wallet", &Wallet{})
+	registry.Register("Player", &Player{})
+	registry.Register("Particle", &Particle{})
+	registry.Register("Bullet", &Bullet{})
+	registry.Register("Enemy", &Enemy{})
+	registry.Register("Diggable", &Diggable{})
+	registry.Register("Projectile", &Projectile{})
+	registry.Register("Camera", &Camera{})
+	registry.Register("Collider", &Collider{})
+
+	registry.RegisterResolver("Interactor", &Interactor{}, ecs.Resolver{
+		Save: func(component interface{}) (interface{}, error) {
+			return struct{}{}, nil
+		},
+		Load: func(data json.RawMessage) (interface{}, error) {
+			return &Interactor{}, nil
+		},
+	})
+
+	registry.RegisterResolver("Renderable", &Renderable{}, ecs.Resolver{
+		Save: func(component interface{}) (interface{}, error) {
+			renderable := component.(*Renderable)
+			saved := savedRenderable{Name: renderable.Name, Layer: renderable.Layer, ZIndex: renderable.ZIndex}
+			if renderable.Sprite != nil {
+				frame := renderable.Sprite.Frame()
+				saved.Frame = &frame
+			}
+			return saved, nil
+		},
+		Load: func(data json.RawMessage) (interface{}, error) {
+			var saved savedRenderable
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return nil, err
+			}
+
+			renderable := &Renderable{Layer: saved.Layer, ZIndex: saved.ZIndex}
+			if saved.Frame != nil {
+				renderable.Sprite = pixel.NewSprite(atlas.Picture, *saved.Frame)
+			}
+
+			if saved.Name != "" {
+				sprite, err := atlas.Sprite(saved.Name)
+				if err != nil {
+					return nil, err
+				}
+
+				renderable.Sprite, renderable.Name = sprite, saved.Name
+			}
+
+			return renderable, nil
+		},
+	})
+
+	registry.RegisterResolver("Animation", &Animation{}, ecs.Resolver{
+		Save: func(component interface{}) (interface{}, error) {
+			animation := component.(*Animation)
+			return savedAnimation{animation.Playing, animation.Frame, animation.Elapsed, animation.Reverse, animation.Finished}, nil
+		},
+		Load: func(data json.RawMessage) (interface{}, error) {
+			var saved savedAnimation
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return nil, err
+			}
+
+			animation, err := atlas.Animation(saved.Playing)
+			if err != nil {
+				return nil, err
+			}
+
+			animation.Frame, animation.Elapsed, animation.Reverse, animation.Finished = saved.Frame, saved.Elapsed, saved.Reverse, saved.Finished
+			return animation, nil
+		},
+	})
+
+	registry.RegisterResolver("Interactive", &Interactive{}, ecs.Resolver{
+		Save: func(component interface{}) (interface{}, error) {
+			interactive := component.(*Interactive)
+			return savedInteractive{interactive.Prompt, interactive.Name}, nil
+		},
+		Load: func(data json.RawMessage) (interface{}, error) {
+			var saved savedInteractive
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return nil, err
+			}
+
+			menu, ok := menus[saved.Name]
+			if !ok {
+				return nil, fmt.Errorf("no menu for interactive %q", saved.Name)
+			}
+
+			return &Interactive{Prompt: saved.Prompt, Name: saved.Name, Menu: menu}, nil
+		},
+	})
+
+	return registry
+}
@@ -0,0 +1,79 @@
+package systems
+
+import (
+	"bytes"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"reflect"
+	"testing"
+)
+
+// newSaveWorld returns an ECS running the systems which own entities or components that are saved, already set up.
+func newSaveWorld(t *testing.T) *ecs.ECS {
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e := &world
+	TransformSystem(e, nil)
+	InteractiveSystem(e, input.Actions{Input: input.NewMemory(pixel.R(0, 0, 1024, 768)), Bindings: input.NewBindings()},
+		nil)
+	stepWithin(t, e, 1, 0)
+	return e
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	atlas, err := LoadAtlas("../sprites.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	menus := map[string]func(ecs.EventContainer) *InteractionMenu{
+		"Alice": func(ecs.EventContainer) *InteractionMenu { return nil },
+	}
+	registry := ComponentRegistry(atlas, menus)
+
+	saving := newSaveWorld(t)
+	defer saving.Close()
+
+	sprite, _ := atlas.Renderable("player")
+	player := saving.AddEntity(&Transform{X: 20, Y: 20}, &Physics{VelX: 5, DragFactor: 0.93}, &Wallet{Balance: 100},
+		&Interactor{}, &Collider{Shape: ShapeCircle, Radius: 24}, sprite)
+	shadow := saving.AddEntity(&Transform{X: 10, ParentID: player}, &Renderable{Layer: LayerWorld})
+	saving.AddEntity(&Transform{X: 200, Y: 200}, &Interactive{Prompt: "Talk", Name: "Alice", Menu: menus["Alice"]})
+	stepWithin(t, saving, 1, 0)
+
+	var saved bytes.Buffer
+	if err := saving.Save(&saved, registry); err != nil {
+		t.Fatal(err)
+	}
+
+	// The loading game has set up its own labels, which mustn't clash with the saved entities.
+	loading := newSaveWorld(t)
+	defer loading.Close()
+	if err := loading.Load(bytes.NewReader(saved.Bytes()), registry); err != nil {
+		t.Fatal(err)
+	}
+	stepWithin(t, loading, 1, 0)
+
+	var resaved bytes.Buffer
+	if err := loading.Save(&resaved, registry); err != nil {
+		t.Fatal(err)
+	}
+	if resaved.String() != saved.String() {
+		t.Errorf("saving the loaded game gave\n%s\nwant\n%s", resaved.String(), saved.String())
+	}
+
+	if labels := loading.Query(reflect.TypeOf(&HUDLine{})); len(labels) != 2 {
+		t.Errorf("got %d labels, want the 2 the interactive system made", len(labels))
+	}
+
+	renderable, _ := ecs.ComponentOf[Renderable](loading, player)
+	if renderable.Name != "player" || renderable.Sprite.Frame() != atlas.Frames["player"] {
+		t.Errorf("player loaded with sprite %q at %v", renderable.Name, renderable.Sprite.Frame())
+	}
+	if renderable, _ := ecs.ComponentOf[Renderable](loading, shadow); renderable.Sprite != nil {
+		t.Error("renderable without a sprite loaded with one")
+	}
+	if transform, _ := ecs.ComponentOf[Transform](loading, shadow); transform.World.X != 30 {
+		t.Errorf("child loaded at %v, want 30 from its parent", transform.World.X)
+	}
+}
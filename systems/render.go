@@ -4,14 +4,12 @@ import (
 	"fmt"
 	"github.com/emctague/go-loopy/ecs"
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 	"github.com/faiface/pixel/text"
 	"golang.org/x/image/font/basicfont"
 	"image"
-	"image/color"
 	_ "image/png"
-	"log"
 	"os"
+	"sort"
 )
 
 // HUDLine is a component which provides a line of text on-screen above all other content.
@@ -19,18 +17,39 @@ type HUDLine struct {
 	Prompt   string  // The contents of the HUD line.
 	Centered bool    // Whether or not the line is centered horizontally on the transform position.
 	FontSize float64 // The font size as a multiplier.
-	width    float64 // The width of the HUDLine with its current prompt value. This is used for centering.
+	ZIndex   int     // Within the UI layer, lines and Renderables with a higher ZIndex are drawn over lower ones.
+
+	// Whether the line is positioned in the world, and so moves with the camera, rather than on the screen, e.g. for
+	// name tags. The text is the same size regardless of the camera's zoom, and is still drawn in the UI layer.
+	WorldSpace bool
 }
 
+// Layer determines which pass an entity is drawn in. Every entity in a layer is drawn over every entity in the
+// layers before it, regardless of ZIndex.
+type Layer int
+
+const (
+	LayerWorld   Layer = iota // The world, e.g. characters and terrain. Drawn through the camera.
+	LayerEffects              // Effects over the world, e.g. bullets and particles. Drawn through the camera.
+	LayerUI                   // The user interface, drawn in screen coordinates along with every HUDLine.
+	layerCount
+)
+
 // Renderable is a component which defines a colored circle to be drawn on-screen by the renderer.
 type Renderable struct {
-	Sprite *pixel.Sprite
+	Sprite *pixel.Sprite // The sprite to draw, or nil to draw nothing.
+	Name   string        // The name of the sprite within its Atlas, if it came from one.
+	Layer  Layer         // The pass the sprite is drawn in.
+	ZIndex int           // Within a layer, sprites with a higher ZIndex are drawn over those with a lower one.
 }
 
-// ChangeHUDPromptEvent represents a request to change the prompt string of a HUDLine.
-type ChangeHUDPromptEvent struct {
-	ID     uint64
-	Prompt string
+// Copy returns a copy of the renderable with its own sprite, so that changing the copy's frame, e.g. by animating it,
+// leaves the original as it was.
+func (r Renderable) Copy() *Renderable {
+	if r.Sprite != nil {
+		r.Sprite = pixel.NewSprite(r.Sprite.Picture(), r.Sprite.Frame())
+	}
+	return &r
 }
 
 type eDebugRenderable struct {
@@ -43,88 +62,148 @@ type eHudText struct {
 	*Transform
 }
 
-// RenderSystem is a system which draws to the screen.
-// Unlike other systems, the RenderSystem does not run itself in a goroutine - because PixelGL requires rendering to
-// occur in the main thread, RenderSystem takes it over and runs the passed whenReady function in a goroutine, where
-// the user of the RenderSystem may continue setup.
-func RenderSystem(e *ecs.ECS, win *pixelgl.Window, whenReady func()) {
-	debugRenderables := make(map[uint64]eDebugRenderable)
-	hudLines := make(map[uint64]eHudText)
+// RenderTarget is anything a Scene can be drawn onto, such as a pixelgl window or a headless canvas.
+type RenderTarget interface {
+	pixel.BasicTarget
+	Bounds() pixel.Rect
+}
+
+// Scene keeps track of the entities which can be drawn, and draws them onto any pixel target. It is shared by render
+// systems, which pass it the entity events they receive and call Draw on every RenderEvent, so that every renderer
+// draws the same way.
+type Scene struct {
+	debugRenderables map[ecs.EntityID]eDebugRenderable
+	hudLines         map[ecs.EntityID]eHudText
+	cameras          map[ecs.EntityID]eCamera
+	txt              *text.Text
+}
 
-	events := e.Subscribe()
+// SceneEvents are the events which a Scene needs to be passed, for render systems to subscribe to.
+var SceneEvents = []interface{}{ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+	ecs.EntityRemovedEvent{}}
 
+// NewScene returns an empty Scene.
+func NewScene() *Scene {
 	atlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
-	txt := text.New(pixel.V(0, 0), atlas)
-
-	go whenReady()
 
-	for ev := range events {
-		switch event := ev.Event.(type) {
-		case ecs.EntityAddedEvent:
-			ecs.UnpackEntity(event, &debugRenderables)
-			ecs.UnpackEntity(event, &hudLines)
+	return &Scene{
+		debugRenderables: make(map[ecs.EntityID]eDebugRenderable),
+		hudLines:         make(map[ecs.EntityID]eHudText),
+		cameras:          make(map[ecs.EntityID]eCamera),
+		txt:              text.New(pixel.V(0, 0), atlas),
+	}
+}
 
-		case ecs.EntityRemovedEvent:
-			ecs.RemoveEntity(event.ID, &debugRenderables)
-			ecs.RemoveEntity(event.ID, &hudLines)
+// Track updates the scene's entities in response to one of the SceneEvents. Other events are ignored.
+func (s *Scene) Track(event interface{}) {
+	switch event := event.(type) {
+	case ecs.EntityChange:
+		ecs.RefreshEntity(event, &s.debugRenderables)
+		ecs.RefreshEntity(event, &s.hudLines)
+		ecs.RefreshEntity(event, &s.cameras)
+
+	case ecs.EntityRemovedEvent:
+		ecs.RemoveEntity(event.ID, &s.debugRenderables)
+		ecs.RemoveEntity(event.ID, &s.hudLines)
+		ecs.RemoveEntity(event.ID, &s.cameras)
+	}
+}
 
-		case ecs.UpdateBeginEvent:
+// Draw draws every tracked entity onto the target, interpolating transforms by the given alpha. Each layer is drawn in
+// turn, and entities within a layer are sorted by ZIndex and then by ID, so that the output is the same every time.
+func (s *Scene) Draw(target RenderTarget, alpha float64) {
+	view := s.viewMatrix(target.Bounds(), alpha)
 
-			if win.Closed() {
-				e.Stop()
-			}
+	var layers [layerCount][]sceneItem
 
-		case ecs.UpdateEndEvent:
+	for id, renderable := range s.debugRenderables {
+		renderable := renderable
+		if renderable.Sprite == nil {
+			continue
+		}
 
-			win.Clear(color.RGBA{R: 0, G: 0, B: 0, A: 255})
+		layer := renderable.Layer
+		if layer < 0 || layer >= layerCount {
+			layer = LayerWorld
+		}
 
-			// Draw all debug circles
-			for _, renderable := range debugRenderables {
-				renderable.Sprite.Draw(win, pixel.IM.Rotated(pixel.V(0, 0), renderable.Rotation).Moved(pixel.V(renderable.X, renderable.Y)))
+		layers[layer] = append(layers[layer], sceneItem{renderable.ZIndex, id, func() {
+			matrix := view
+			if layer == LayerUI {
+				matrix = pixel.IM
 			}
 
-			// Draw all HUD lines
-			for _, hudLine := range hudLines {
-				txt.Clear()
-
-				if hudLine.Centered {
-					txt.Dot.X -= hudLine.width / 2
-				}
+			renderable.Sprite.Draw(target, renderable.Interpolated(alpha).Matrix().Chained(matrix))
+		}})
+	}
 
-				_, _ = fmt.Fprintln(txt, hudLine.Prompt)
+	for id, hudLine := range s.hudLines {
+		hudLine := hudLine
+		layers[LayerUI] = append(layers[LayerUI], sceneItem{hudLine.ZIndex, id, func() {
+			s.drawHUDLine(target, hudLine, view, alpha)
+		}})
+	}
 
-				win.SetMatrix(pixel.IM.Moved(pixel.V(hudLine.X, hudLine.Y)))
-				txt.Draw(win, pixel.IM.Scaled(txt.Orig, hudLine.FontSize))
+	for _, items := range layers {
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].zIndex != items[j].zIndex {
+				return items[i].zIndex < items[j].zIndex
 			}
+			return items[i].id < items[j].id
+		})
 
-			win.SetMatrix(pixel.IM)
-			win.Update()
+		for _, item := range items {
+			item.draw()
+		}
+	}
+}
 
-		case ChangeHUDPromptEvent:
-			line, ok := hudLines[event.ID]
-			if !ok {
-				log.Fatal("Cannot change prompt on an entity with no HUDLine component")
-			}
+// sceneItem is a single entity to be drawn within a layer.
+type sceneItem struct {
+	zIndex int
+	id     ecs.EntityID
+	draw   func()
+}
 
-			if event.Prompt == line.Prompt {
-				break
-			}
+// drawHUDLine draws a HUDLine's text, placing world space lines through the given view matrix.
+func (s *Scene) drawHUDLine(target RenderTarget, hudLine eHudText, view pixel.Matrix, alpha float64) {
+	s.txt.Clear()
 
-			line.HUDLine.Prompt = event.Prompt
+	if hudLine.Centered {
+		s.txt.Dot.X -= s.txt.BoundsOf(hudLine.Prompt).W() / 2
+	}
 
-			if event.Prompt == "" {
-				line.width = 0
-				break
-			}
+	_, _ = fmt.Fprintln(s.txt, hudLine.Prompt)
 
-			txt.Clear()
-			line.width = txt.BoundsOf(line.Prompt).W()
+	position := hudLine.Interpolated(alpha).Position()
+	if hudLine.WorldSpace {
+		position = view.Project(position)
+	}
 
-		}
+	target.SetMatrix(pixel.IM.Moved(position))
+	s.txt.Draw(target, pixel.IM.Scaled(s.txt.Orig, hudLine.FontSize))
+	target.SetMatrix(pixel.IM)
+}
+
+// viewMatrix returns the matrix which projects world coordinates onto the screen, through the camera with the lowest ID.
+func (s *Scene) viewMatrix(viewport pixel.Rect, alpha float64) pixel.Matrix {
+	ids := sortedIDs(s.cameras)
+	if len(ids) == 0 {
+		return pixel.IM
+	}
+
+	return s.cameras[ids[0]].Matrix(viewport, alpha)
+}
 
-		ev.Wg.Done()
+// sortedIDs returns the keys of an entity map in ascending order.
+func sortedIDs[T any](entities map[ecs.EntityID]T) []ecs.EntityID {
+	ids := make([]ecs.EntityID, 0, len(entities))
+	for id := range entities {
+		ids = append(ids, id)
 	}
 
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
 }
 
 // From pixelGL tutorials
@@ -0,0 +1,130 @@
+package systems
+
+import (
+	"bytes"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"strings"
+	"testing"
+)
+
+// replayFrames is how many frames the replay test records.
+const replayFrames = 120
+
+// scriptedClock plays the part of a player, changing the input before each frame.
+type scriptedClock struct {
+	memory *input.Memory
+	frame  int
+}
+
+// Tick moves around, turns and fires over the course of the recording.
+func (c *scriptedClock) Tick() float64 {
+	c.memory.EndFrame()
+	c.memory.MoveMouse(pixel.V(float64(c.frame*8), 600-float64(c.frame*3)))
+
+	switch c.frame {
+	case 0:
+		c.memory.Press(input.KeyW)
+	case 30:
+		c.memory.Release(input.KeyW)
+		c.memory.Press(input.KeyD)
+	case 60:
+		c.memory.Release(input.KeyD)
+	}
+
+	if c.frame%20 == 10 {
+		c.memory.Press(input.MouseButtonLeft)
+	} else {
+		c.memory.Release(input.MouseButtonLeft)
+	}
+
+	c.frame++
+	return 1.0 / 60
+}
+
+// stopAfter stops the ECS once its clock has ticked a number of times, so that the frame of the last tick is the last
+// one to run.
+type stopAfter struct {
+	ecs.Clock
+	e      *ecs.ECS
+	frames int
+}
+
+func (s *stopAfter) Tick() float64 {
+	delta := s.Clock.Tick()
+	if s.frames--; s.frames == 0 {
+		s.e.Stop()
+	}
+	return delta
+}
+
+// playReplayGame runs a small game against the given input and clock until it stops, returning a snapshot of the
+// resulting state.
+func playReplayGame(t *testing.T, in input.Input, clock func(e *ecs.ECS) ecs.Clock) string {
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e := &world
+	e.Clock = clock(e)
+
+	actions := input.Actions{Input: in, Bindings: DefaultBindings()}
+	bullet := Renderable{Sprite: pixel.NewSprite(nil, pixel.R(0, 0, 8, 8))}
+
+	index := NewSpatialIndex(64)
+	TransformSystem(e, index)
+	PhysicsSystem(e, pixel.ZV)
+	PlayerSystem(e, actions, bullet)
+	ProjectileSystem(e, in, index)
+	BulletSystem(e, index)
+	CollisionSystem(e, index)
+
+	e.AddEntity(&Transform{X: 200, Y: 200}, &Physics{DragFactor: 0.93}, &Player{}, &Interactor{},
+		&Collider{Shape: ShapeCircle, Radius: 24, Layer: CollisionLayerPlayer})
+	for i := 0; i < 5; i++ {
+		e.AddEntity(&Transform{X: 300 + float64(i)*100, Y: 400, Width: 27, Height: 27}, &Enemy{Health: 2},
+			&Collider{Layer: CollisionLayerEnemy})
+	}
+
+	checkErrors(t, e, e.Run())
+
+	var saved bytes.Buffer
+	if err := e.Save(&saved, ComponentRegistry(nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	return saved.String()
+}
+
+func TestReplayReproducesRecording(t *testing.T) {
+	memory := input.NewMemory(pixel.R(0, 0, 1024, 768))
+	var recording bytes.Buffer
+	var recorder *input.Recorder
+
+	recorded := playReplayGame(t, memory, func(e *ecs.ECS) ecs.Clock {
+		recorder = input.NewRecorder(memory, &stopAfter{&scriptedClock{memory: memory}, e, replayFrames}, &recording)
+		return recorder
+	})
+	if recorder.Err != nil {
+		t.Fatal(recorder.Err)
+	}
+
+	playback, err := input.LoadPlayback(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playback.Frames) != replayFrames {
+		t.Fatalf("recorded %d frames, want %d", len(playback.Frames), replayFrames)
+	}
+
+	replayed := playReplayGame(t, playback, func(e *ecs.ECS) ecs.Clock {
+		return &stopAfter{playback, e, replayFrames}
+	})
+
+	if replayed != recorded {
+		t.Errorf("replay ended in\n%s\nwant\n%s", replayed, recorded)
+	}
+
+	// Make sure the recording did something worth replaying.
+	if !strings.Contains(recorded, `"Bullet":{}`) {
+		t.Errorf("no bullets were fired in the recording:\n%s", recorded)
+	}
+}
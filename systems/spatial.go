@@ -0,0 +1,313 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"sort"
+	"sync"
+)
+
+// SpatialIndex is a spatial hash of entity positions, which finds entities near a point or within an area without
+// checking every entity. Entities are stored as points, so queries for entities which take up space should be widened
+// by the entities' size, which can be recorded as their extent. The TransformSystem keeps an index up to date with the
+// position of every Transform, and the CollisionSystem records the extent of every Collider.
+type SpatialIndex struct {
+	mutex     sync.RWMutex
+	cellSize  float64
+	cells     map[spatialCell][]ecs.EntityID
+	positions map[ecs.EntityID]pixel.Vec
+	extents   extentSet
+}
+
+// extentSet keeps track of how far each of a set of entities reaches, and of the furthest any of them reaches.
+type extentSet struct {
+	extents map[ecs.EntityID]float64
+	max     float64
+	stale   bool // True if max needs to be worked out again, since the largest extent may have shrunk.
+}
+
+// newExtentSet returns an empty extentSet.
+func newExtentSet() extentSet {
+	return extentSet{extents: make(map[ecs.EntityID]float64)}
+}
+
+// set records how far an entity reaches.
+func (s *extentSet) set(id ecs.EntityID, extent float64) {
+	if old, ok := s.extents[id]; ok && old >= s.max && extent < old {
+		s.stale = true
+	}
+	s.extents[id] = extent
+	s.max = math.Max(s.max, extent)
+}
+
+// remove forgets an entity's extent, if it has one.
+func (s *extentSet) remove(id ecs.EntityID) {
+	if old, ok := s.extents[id]; ok {
+		if old >= s.max {
+			s.stale = true
+		}
+		delete(s.extents, id)
+	}
+}
+
+// maximum returns the furthest any entity reaches.
+func (s *extentSet) maximum() float64 {
+	if s.stale {
+		s.max = 0
+		for _, extent := range s.extents {
+			s.max = math.Max(s.max, extent)
+		}
+		s.stale = false
+	}
+	return s.max
+}
+
+// spatialCell identifies a square cell of the index.
+type spatialCell struct {
+	x, y int
+}
+
+// NewSpatialIndex returns an empty index which groups entities into square cells of the given size. Queries are
+// fastest when the cell size is close to the size of the areas usually queried.
+func NewSpatialIndex(cellSize float64) *SpatialIndex {
+	return &SpatialIndex{
+		cellSize:  cellSize,
+		cells:     make(map[spatialCell][]ecs.EntityID),
+		positions: make(map[ecs.EntityID]pixel.Vec),
+		extents:   newExtentSet(),
+	}
+}
+
+// Set adds the entity to the index at the given position, or moves it there if it is already in the index.
+func (s *SpatialIndex) Set(id ecs.EntityID, position pixel.Vec) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if old, ok := s.positions[id]; ok {
+		if s.cellOf(old) == s.cellOf(position) {
+			s.positions[id] = position
+			return
+		}
+
+		s.removeFromCell(id, s.cellOf(old))
+	}
+
+	cell := s.cellOf(position)
+	s.cells[cell] = append(s.cells[cell], id)
+	s.positions[id] = position
+}
+
+// Remove removes the entity from the index, if it is in it.
+func (s *SpatialIndex) Remove(id ecs.EntityID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if position, ok := s.positions[id]; ok {
+		s.removeFromCell(id, s.cellOf(position))
+		delete(s.positions, id)
+	}
+
+	s.extents.remove(id)
+}
+
+// SetExtent records how far the entity reaches from its position along either axis, so that queries for things
+// touching the entity can be widened by MaxExtent.
+func (s *SpatialIndex) SetExtent(id ecs.EntityID, extent float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.extents.set(id, extent)
+}
+
+// RemoveExtent forgets how far the entity reaches, e.g. once it no longer takes up space, while leaving its position
+// in the index.
+func (s *SpatialIndex) RemoveExtent(id ecs.EntityID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.extents.remove(id)
+}
+
+// MaxExtent returns the largest extent of any entity in the index.
+func (s *SpatialIndex) MaxExtent() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.extents.maximum()
+}
+
+// Position returns the position of the entity in the index.
+func (s *SpatialIndex) Position(id ecs.EntityID) (pixel.Vec, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	position, ok := s.positions[id]
+	return position, ok
+}
+
+// Len returns the number of entities in the index.
+func (s *SpatialIndex) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.positions)
+}
+
+// QueryRect returns every entity positioned within the given rectangle, including its edges, in order of ID.
+func (s *SpatialIndex) QueryRect(rect pixel.Rect) []ecs.EntityID {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rect = rect.Norm()
+	return s.query(rect, func(position pixel.Vec) bool {
+		return position.X >= rect.Min.X && position.X <= rect.Max.X && position.Y >= rect.Min.Y && position.Y <= rect.Max.Y
+	})
+}
+
+// QueryRadius returns every entity positioned within the given distance of center, in order of ID.
+func (s *SpatialIndex) QueryRadius(center pixel.Vec, radius float64) []ecs.EntityID {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rect := pixel.R(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius)
+	return s.query(rect, func(position pixel.Vec) bool {
+		return position.To(center).Len() <= radius
+	})
+}
+
+// Nearest returns the entity closest to the given point, within the given distance, for which filter returns true.
+// A nil filter accepts every entity, and an infinite distance finds the nearest entity anywhere. If several entities
+// are equally close, the one with the lowest ID is returned.
+func (s *SpatialIndex) Nearest(point pixel.Vec, maxDistance float64, filter func(ecs.EntityID) bool) (ecs.EntityID, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var nearest ecs.EntityID
+	nearestDistance := math.Inf(1)
+
+	consider := func(id ecs.EntityID) {
+		distance := s.positions[id].To(point).Len()
+		if distance > maxDistance || distance > nearestDistance || (distance == nearestDistance && id > nearest) ||
+			(filter != nil && !filter(id)) {
+			return
+		}
+
+		nearest, nearestDistance = id, distance
+	}
+
+	// Search rings of cells moving outwards from the point's cell. Every entity in ring n+1 is at least n cells away
+	// from the point, so the search can stop once something closer than that has been found.
+	center := s.cellOf(point)
+	for ring := 0; ; ring++ {
+		minRingDistance := float64(ring-1) * s.cellSize
+		if minRingDistance > maxDistance || minRingDistance > nearestDistance {
+			break
+		}
+
+		// Once rings have more cells than there are occupied cells, the remaining entities are sparse, so it's quicker
+		// to check every occupied cell outside of the rings searched so far.
+		if 8*ring > len(s.cells) {
+			for cell, ids := range s.cells {
+				if chebyshev(cell, center) >= ring {
+					for _, id := range ids {
+						consider(id)
+					}
+				}
+			}
+			break
+		}
+
+		for _, cell := range ringCells(center, ring) {
+			for _, id := range s.cells[cell] {
+				consider(id)
+			}
+		}
+	}
+
+	return nearest, nearest != 0
+}
+
+// query returns the entities within cells overlapping the given rectangle whose positions pass the given test.
+// The mutex must be held.
+func (s *SpatialIndex) query(rect pixel.Rect, test func(position pixel.Vec) bool) []ecs.EntityID {
+	var found []ecs.EntityID
+
+	// With a huge rectangle, it's quicker to check every entity than every cell.
+	if (rect.W()/s.cellSize+1)*(rect.H()/s.cellSize+1) > float64(len(s.positions)) {
+		for id, position := range s.positions {
+			if test(position) {
+				found = append(found, id)
+			}
+		}
+	} else {
+		min, max := s.cellOf(rect.Min), s.cellOf(rect.Max)
+		for x := min.x; x <= max.x; x++ {
+			for y := min.y; y <= max.y; y++ {
+				for _, id := range s.cells[spatialCell{x, y}] {
+					if test(s.positions[id]) {
+						found = append(found, id)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i] < found[j] })
+	return found
+}
+
+// cellOf returns the cell containing the given position.
+func (s *SpatialIndex) cellOf(position pixel.Vec) spatialCell {
+	return spatialCell{int(math.Floor(position.X / s.cellSize)), int(math.Floor(position.Y / s.cellSize))}
+}
+
+// removeFromCell removes the entity from the list of entities in the given cell. The mutex must be held.
+func (s *SpatialIndex) removeFromCell(id ecs.EntityID, cell spatialCell) {
+	ids := s.cells[cell]
+	for i, other := range ids {
+		if other == id {
+			ids[i] = ids[len(ids)-1]
+			ids = ids[:len(ids)-1]
+			break
+		}
+	}
+
+	if len(ids) == 0 {
+		delete(s.cells, cell)
+	} else {
+		s.cells[cell] = ids
+	}
+}
+
+// chebyshev returns the number of rings between two cells.
+func chebyshev(a spatialCell, b spatialCell) int {
+	dx, dy := a.x-b.x, a.y-b.y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// ringCells returns the cells which are exactly the given number of cells away from center, horizontally or
+// vertically, forming a square ring around it.
+func ringCells(center spatialCell, ring int) []spatialCell {
+	if ring == 0 {
+		return []spatialCell{center}
+	}
+
+	cells := make([]spatialCell, 0, 8*ring)
+	for i := -ring; i <= ring; i++ {
+		cells = append(cells, spatialCell{center.x + i, center.y - ring}, spatialCell{center.x + i, center.y + ring})
+	}
+	for i := -ring + 1; i < ring; i++ {
+		cells = append(cells, spatialCell{center.x - ring, center.y + i}, spatialCell{center.x + ring, center.y + i})
+	}
+	return cells
+}
@@ -0,0 +1,210 @@
+package systems
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/faiface/pixel"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// benchmarkEntities is how many entities the benchmarks are run with.
+const benchmarkEntities = 10000
+
+// benchmarkSpacing is the distance between neighbouring entities in the benchmarks' grid, which is wide enough that
+// their colliders never touch.
+const benchmarkSpacing = 20
+
+// gridPositions returns n positions laid out in a square grid.
+func gridPositions(n int) []pixel.Vec {
+	side := int(math.Ceil(math.Sqrt(float64(n))))
+	positions := make([]pixel.Vec, n)
+	for i := range positions {
+		positions[i] = pixel.V(float64(i%side), float64(i/side)).Scaled(benchmarkSpacing)
+	}
+	return positions
+}
+
+// randomPoints returns n points within the grid of the given number of entities, the same every time.
+func randomPoints(n int, entities int) []pixel.Vec {
+	size := math.Sqrt(float64(entities)) * benchmarkSpacing
+	random := rand.New(rand.NewSource(1))
+	points := make([]pixel.Vec, n)
+	for i := range points {
+		points[i] = pixel.V(random.Float64()*size, random.Float64()*size)
+	}
+	return points
+}
+
+// loadGrid adds an enemy with a small circle collider at each position. The entities are loaded as a snapshot, which
+// adds them all in one update rather than a few at a time.
+func loadGrid(tb testing.TB, e *ecs.ECS, positions []pixel.Vec) {
+	tb.Helper()
+
+	registry := ecs.NewComponentRegistry()
+	registry.Register("Transform", &Transform{})
+	registry.Register("Collider", &Collider{})
+	registry.Register("Enemy", &Enemy{})
+
+	var snapshot ecs.Snapshot
+	for i, position := range positions {
+		components := map[string]interface{}{
+			"Transform": &Transform{X: position.X, Y: position.Y},
+			"Collider":  &Collider{Shape: ShapeCircle, Radius: 4, Trigger: true},
+			"Enemy":     &Enemy{Health: 1},
+		}
+
+		entity := ecs.EntitySnapshot{ID: ecs.NewEntityID(uint32(i+1), 0), Components: make(map[string]json.RawMessage)}
+		for name, component := range components {
+			entity.Components[name], _ = json.Marshal(component)
+		}
+		snapshot.Entities = append(snapshot.Entities, entity)
+	}
+
+	var saved bytes.Buffer
+	if err := json.NewEncoder(&saved).Encode(snapshot); err != nil {
+		tb.Fatal(err)
+	}
+	if err := e.Load(&saved, registry); err != nil {
+		tb.Fatal(err)
+	}
+	checkErrors(tb, e, e.Step(1, 0))
+}
+
+func BenchmarkSpatialIndexQueryRect(b *testing.B) {
+	index := NewSpatialIndex(64)
+	positions := gridPositions(benchmarkEntities)
+	for i, position := range positions {
+		index.Set(ecs.NewEntityID(uint32(i+1), 0), position)
+	}
+	points := randomPoints(1000, benchmarkEntities)
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			point := points[i%len(points)]
+			index.QueryRect(pixel.R(point.X-50, point.Y-50, point.X+50, point.Y+50))
+		}
+	})
+
+	b.Run("unindexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			point := points[i%len(points)]
+			rect := pixel.R(point.X-50, point.Y-50, point.X+50, point.Y+50)
+
+			var found []ecs.EntityID
+			for id, position := range positions {
+				if rect.Contains(position) {
+					found = append(found, ecs.NewEntityID(uint32(id+1), 0))
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkSpatialIndexNearest(b *testing.B) {
+	index := NewSpatialIndex(64)
+	positions := gridPositions(benchmarkEntities)
+	for i, position := range positions {
+		index.Set(ecs.NewEntityID(uint32(i+1), 0), position)
+	}
+	points := randomPoints(1000, benchmarkEntities)
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.Nearest(points[i%len(points)], math.Inf(1), nil)
+		}
+	})
+
+	b.Run("unindexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			point := points[i%len(points)]
+
+			nearest, nearestDistance := -1, math.Inf(1)
+			for id, position := range positions {
+				if distance := position.To(point).Len(); distance < nearestDistance {
+					nearest, nearestDistance = id, distance
+				}
+			}
+			_ = nearest
+		}
+	})
+}
+
+func BenchmarkCollisionSystem(b *testing.B) {
+	for _, indexed := range []bool{true, false} {
+		name := "unindexed"
+		var index *SpatialIndex
+		if indexed {
+			name, index = "indexed", NewSpatialIndex(64)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			e := ecs.NewECS()
+			e.ErrorPolicy = ecs.ErrorPolicyCollect
+			defer e.Close()
+			TransformSystem(&e, index)
+			CollisionSystem(&e, index)
+			loadGrid(b, &e, gridPositions(benchmarkEntities))
+
+			b.ResetTimer()
+			checkErrors(b, &e, e.Step(b.N, 1.0/60))
+		})
+	}
+}
+
+func BenchmarkShapeCast(b *testing.B) {
+	e := ecs.NewECS()
+	e.ErrorPolicy = ecs.ErrorPolicyCollect
+	defer e.Close()
+	index := NewSpatialIndex(64)
+	TransformSystem(&e, index)
+	CollisionSystem(&e, index)
+	loadGrid(b, &e, gridPositions(benchmarkEntities))
+
+	// Bullet-sized sweeps across a few cells.
+	bullet := &Collider{Shape: ShapeCircle, Radius: 2}
+	points := randomPoints(1000, benchmarkEntities)
+
+	for _, index := range []*SpatialIndex{index, nil} {
+		name := "unindexed"
+		if index != nil {
+			name = "indexed"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				from := points[i%len(points)]
+				ShapeCast(&e, index, bullet, &Transform{}, from, from.Add(pixel.V(30, 10)), nil)
+			}
+		})
+	}
+}
+
+func TestBulletSystemHitsEnemyBetweenUpdates(t *testing.T) {
+	for _, index := range []*SpatialIndex{nil, NewSpatialIndex(64)} {
+		e := ecs.NewECS()
+		e.ErrorPolicy = ecs.ErrorPolicyCollect
+		TransformSystem(&e, index)
+		PhysicsSystem(&e, pixel.ZV)
+		CollisionSystem(&e, index)
+		BulletSystem(&e, index)
+
+		enemy := &Enemy{Health: 2}
+		enemyID := e.AddEntity(&Transform{X: 100}, &Collider{Shape: ShapeCircle, Radius: 5, Trigger: true}, enemy)
+		stepWithin(t, &e, 1, 0)
+
+		// The bullet starts and ends each update well clear of the enemy.
+		bullet := e.AddEntity(&Transform{}, &Physics{VelX: 12000, DragFactor: 1},
+			&Collider{Shape: ShapeCircle, Radius: 2, Trigger: true}, &Bullet{})
+		stepWithin(t, &e, 1, 0)
+		stepWithin(t, &e, 2, 1.0/60)
+
+		if enemy.Health != 1 || e.Alive(bullet) || !e.Alive(enemyID) {
+			t.Errorf("indexed: %v: enemy has %d health and bullet alive: %v, want 1 and the bullet used up",
+				index != nil, enemy.Health, e.Alive(bullet))
+		}
+		e.Close()
+	}
+}
@@ -1,126 +1,461 @@
 package systems
 
 import (
+	"errors"
 	"github.com/emctague/go-loopy/ecs"
-	"log"
+	"github.com/faiface/pixel"
+	"math"
+	"reflect"
+	"sort"
 )
 
-// Transform is a component which represents the position of some entity.
+// Transform is a component which represents the position of some entity. The position, rotation and scale are
+// relative to the entity's parent, if it has one, and the TransformSystem works out where that places the entity in the
+// world. Without a parent, they are the same as the entity's world transform.
 type Transform struct {
 	X        float64
 	Y        float64
 	Rotation float64
+	ScaleX   float64 // The horizontal scale, or 0 for 1.
+	ScaleY   float64 // The vertical scale, or 0 for 1.
 	Width    float64
 	Height   float64
-	ParentID uint64 // This transform will follow all the same movements as its parent. Set to 0 for 'no parent'.
+	ParentID ecs.EntityID // This transform will follow all the same movements as its parent. Set to 0 for 'no parent'.
+
+	OnParentRemoved ParentRemovalPolicy // What happens to this entity when its parent is removed.
+
+	World WorldTransform // Where the entity is in the world, taking its parents into account.
+	Prev  WorldTransform // The world transform at the end of the previous update, used for interpolation.
+
+	last  WorldTransform // The world transform at the end of the latest update.
+	local WorldTransform // The local transform that World was last worked out from.
 }
 
-// TransformEvent represents a change in the position of an entity.
-// This may then fire transform events for any transforms which use the given entity as a parent.
+// ParentRemovalPolicy decides what happens to an entity when its parent is removed or loses its Transform.
+type ParentRemovalPolicy int
+
+const (
+	DetachFromParent      ParentRemovalPolicy = iota // The entity is left without a parent, where it was in the world.
+	DestroyWithParent                                // The entity is removed too, applying its children's policies.
+	ReparentToGrandparent                            // The entity is given its parent's parent, where it was in the world.
+)
+
+// WorldTransform is the position, rotation and scale of an entity in the world. The TransformSystem keeps one up to
+// date for every Transform.
+type WorldTransform struct {
+	X        float64
+	Y        float64
+	Rotation float64
+	ScaleX   float64
+	ScaleY   float64
+}
+
+// identityTransform is the world transform of entities without a parent.
+var identityTransform = WorldTransform{ScaleX: 1, ScaleY: 1}
+
+// Matrix returns the matrix which moves points from the transform's local space into the world.
+func (w WorldTransform) Matrix() pixel.Matrix {
+	return pixel.IM.ScaledXY(pixel.ZV, pixel.V(w.ScaleX, w.ScaleY)).Rotated(pixel.ZV, w.Rotation).Moved(w.Position())
+}
+
+// Position returns the transform's position as a vector.
+func (w WorldTransform) Position() pixel.Vec {
+	return pixel.V(w.X, w.Y)
+}
+
+// child returns the world transform of a child with the given local transform.
+func (w WorldTransform) child(local WorldTransform) WorldTransform {
+	position := w.Matrix().Project(local.Position())
+	return WorldTransform{position.X, position.Y, w.Rotation + local.Rotation, w.ScaleX * local.ScaleX,
+		w.ScaleY * local.ScaleY}
+}
+
+// localTo returns the local transform which a child of the given parent needs in order to end up at this world
+// transform.
+func (w WorldTransform) localTo(parent WorldTransform) WorldTransform {
+	position := parent.Matrix().Unproject(w.Position())
+	local := WorldTransform{position.X, position.Y, w.Rotation - parent.Rotation, w.ScaleX, w.ScaleY}
+	if parent.ScaleX != 0 {
+		local.ScaleX /= parent.ScaleX
+	}
+	if parent.ScaleY != 0 {
+		local.ScaleY /= parent.ScaleY
+	}
+	return local
+}
+
+// Interpolated returns the world transform blended between the end of the previous update (alpha = 0) and its current
+// state (alpha = 1).
+func (t *Transform) Interpolated(alpha float64) WorldTransform {
+	// Rotate the shortest way around.
+	turn := math.Remainder(t.World.Rotation-t.Prev.Rotation, 2*math.Pi)
+
+	return WorldTransform{
+		X:        t.Prev.X + (t.World.X-t.Prev.X)*alpha,
+		Y:        t.Prev.Y + (t.World.Y-t.Prev.Y)*alpha,
+		Rotation: t.Prev.Rotation + turn*alpha,
+		ScaleX:   t.Prev.ScaleX + (t.World.ScaleX-t.Prev.ScaleX)*alpha,
+		ScaleY:   t.Prev.ScaleY + (t.World.ScaleY-t.Prev.ScaleY)*alpha,
+	}
+}
+
+// localTransform returns the transform's position, rotation and scale relative to its parent, with the default scale
+// applied.
+func (t *Transform) localTransform() WorldTransform {
+	local := WorldTransform{t.X, t.Y, t.Rotation, t.ScaleX, t.ScaleY}
+	if local.ScaleX == 0 {
+		local.ScaleX = 1
+	}
+	if local.ScaleY == 0 {
+		local.ScaleY = 1
+	}
+	return local
+}
+
+// setLocal changes the transform's position, rotation and scale relative to its parent.
+func (t *Transform) setLocal(local WorldTransform) {
+	t.X, t.Y, t.Rotation, t.ScaleX, t.ScaleY = local.X, local.Y, local.Rotation, local.ScaleX, local.ScaleY
+}
+
+// lastPosition returns the world position at the end of the latest update. Until the current update ends, this is
+// where the transform started it.
+func (t *Transform) lastPosition() pixel.Vec {
+	return t.last.Position()
+}
+
+// parentWorldOf returns the world transform of the transform's parent, or the identity if it has none.
+func parentWorldOf(e *ecs.ECS, t *Transform) WorldTransform {
+	if t.ParentID == 0 {
+		return identityTransform
+	}
+	if parent, ok := ecs.ComponentOf[Transform](e, t.ParentID); ok {
+		return parent.World
+	}
+	return identityTransform
+}
+
+// settle records the current world transform as that of the latest update, shifting the old one into Prev.
+func (t *Transform) settle() {
+	t.Prev, t.last = t.last, t.World
+}
+
+// TransformEvent represents a change in the position of an entity. Offsets are relative to the entity's parent, so
+// they are rotated and scaled along with it, while absolute positions are in the world. Children of the entity move
+// along with it.
 type TransformEvent struct {
-	EntityID uint64 // The entity to transform.
+	EntityID ecs.EntityID // The entity to transform.
 	OffsetX  float64
 	OffsetY  float64
-	Absolute bool // True if offsets are actually absolute screen coordinates.
+	Absolute bool // True if offsets are actually absolute world coordinates.
+}
+
+// TargetEntity returns the entity being transformed.
+func (t TransformEvent) TargetEntity() ecs.EntityID {
+	return t.EntityID
+}
+
+// RotateEvent turns an entity by the given number of radians, relative to its parent. Children turn along with it.
+type RotateEvent struct {
+	EntityID ecs.EntityID // The entity to rotate.
+	Offset   float64
+}
+
+// TargetEntity returns the entity being rotated.
+func (r RotateEvent) TargetEntity() ecs.EntityID {
+	return r.EntityID
 }
 
 // SetTransformParentEvent changes which entity a transform is parented to.
-// This does not change the current position of the entity.
+// This does not change the current world transform of the entity.
 type SetTransformParentEvent struct {
-	EntityID uint64 // The entity whose parent should be changed.
-	ParentID uint64 // The new parent for the entity.
+	EntityID ecs.EntityID // The entity whose parent should be changed.
+	ParentID ecs.EntityID // The new parent for the entity.
+}
+
+// TargetEntity returns the entity whose parent is being changed.
+func (s SetTransformParentEvent) TargetEntity() ecs.EntityID {
+	return s.EntityID
+}
+
+// ParentRemovedEvent is published for each child of an entity which is removed or loses its Transform, once the
+// child's ParentRemovalPolicy has been applied. Children which are destroyed get this before their EntityRemovedEvent.
+type ParentRemovedEvent struct {
+	ID          ecs.EntityID // The child whose parent was removed.
+	ParentID    ecs.EntityID // The parent that was removed.
+	NewParentID ecs.EntityID // The child's new parent, or 0 if it has none.
+	Policy      ParentRemovalPolicy
+}
+
+// TargetEntity returns the child whose parent was removed.
+func (p ParentRemovedEvent) TargetEntity() ecs.EntityID {
+	return p.ID
+}
+
+// TransformsRefreshedEvent is published at the end of every update, once the transform system has brought every world
+// transform up to date. Systems which read world transforms at the end of an update should handle it rather than
+// UpdateEndEvent, so that they run after the transform system instead of alongside it.
+type TransformsRefreshedEvent struct {
+	Delta float64
 }
 
 type eTransform struct{ *Transform }
-type eTransformParent struct{ EntityID uint64 }
-
-// TransformSystem keeps track of the transformation of entities and parenting of entity positions to those of other
-// entities.
-func TransformSystem(e *ecs.ECS) {
-	events := e.Subscribe()
-	entities := make(map[uint64]eTransform)
-	parents := make(map[uint64][]eTransformParent)
-
-	go func() {
-
-		for ev := range events {
-			switch event := ev.Event.(type) {
-			case ecs.EntityAddedEvent:
-				addedSet := ecs.UnpackEntity(event, &entities)
-				if addedSet == nil {
-					break
-				}
-				addedCSet := addedSet.(*eTransform)
+type eTransformParent struct{ EntityID ecs.EntityID }
 
-				if addedCSet.ParentID != 0 {
-					tempParentID := addedCSet.ParentID
-					addedCSet.ParentID = 0
-					setParent(&entities, &parents, event.ID, tempParentID)
-				}
+// TransformSystem keeps track of the transformation of entities and parenting of entity transforms to those of other
+// entities, working out the world transform of each entity from its own and its parents'. If index isn't nil, it is
+// kept up to date with the world position of every transform.
+func TransformSystem(e *ecs.ECS, index *SpatialIndex) {
+	events := e.SubscribeTo(ecs.EntityAddedEvent{}, ecs.ComponentAddedEvent{}, ecs.ComponentRemovedEvent{},
+		ecs.EntityRemovedEvent{}, ecs.UpdateEndEvent{}, SetTransformParentEvent{}, TransformEvent{}, RotateEvent{})
+	entities := make(map[ecs.EntityID]eTransform)
+	parents := make(map[ecs.EntityID][]eTransformParent)
 
-			case ecs.EntityRemovedEvent:
-				// Change the parent to no-parent so that the entity is removed from any child lists.
-				if entity, ok := entities[event.ID]; ok && entity.ParentID != 0 {
-					setParent(&entities, &parents, event.ID, 0)
-				}
+	// parentWorld returns the world transform of an entity's parent, or the identity if it has none.
+	parentWorld := func(entity eTransform) WorldTransform {
+		if parent, ok := entities[entity.ParentID]; ok && entity.ParentID != 0 {
+			return parent.World
+		}
+		return identityTransform
+	}
 
-				// Remove from parent list if appropriate
-				if _, ok := parents[event.ID]; ok {
-					delete(parents, event.ID)
-				}
+	// compute works out the world transform of a single entity from its parent's, which must be up to date.
+	compute := func(id ecs.EntityID) {
+		entity := entities[id]
+		entity.local = entity.localTransform()
+		entity.World = parentWorld(entity).child(entity.local)
 
-				ecs.RemoveEntity(event.ID, &entities)
+		if index != nil {
+			index.Set(id, entity.World.Position())
+		}
+	}
 
-			case SetTransformParentEvent:
-				setParent(&entities, &parents, event.EntityID, event.ParentID)
+	// refresh works out the world transform of an entity and all of its descendants.
+	var refresh func(id ecs.EntityID)
+	refresh = func(id ecs.EntityID) {
+		compute(id)
+		for _, child := range parents[id] {
+			refresh(child.EntityID)
+		}
+	}
 
-			case TransformEvent:
-				transformedEntity, ok := entities[event.EntityID]
-				if !ok {
-					log.Fatal("Transform event on entity without a transform!")
+	// refreshChanged works out the world transform of every descendant of an entity whose local transform has been
+	// changed directly, or who has an ancestor which has been.
+	var refreshChanged func(id ecs.EntityID, dirty bool)
+	refreshChanged = func(id ecs.EntityID, dirty bool) {
+		entity := entities[id]
+		if dirty = dirty || entity.localTransform() != entity.local; dirty {
+			compute(id)
+		}
+
+		for _, child := range parents[id] {
+			refreshChanged(child.EntityID, dirty)
+		}
+	}
+
+	// track begins tracking the transform of a changed entity, attaching it to its parent.
+	track := func(event ecs.EntityChange) {
+		id, _ := event.ChangedEntity()
+		addedCSet := ecs.RefreshEntity(event, &entities).(*eTransform)
+
+		if addedCSet.ParentID != 0 {
+			tempParentID := addedCSet.ParentID
+			addedCSet.ParentID = 0
+			if err := setParent(&entities, &parents, id, tempParentID); err != nil {
+				e.ReportError("TransformSystem", event, err)
+			}
+		}
+
+		// Start out without anything to interpolate from.
+		refresh(id)
+		addedCSet.last = addedCSet.World
+		addedCSet.Prev = addedCSet.World
+	}
+
+	// orphan applies the removal policy of each child of an entity which has been removed, given the removed entity's
+	// own parent.
+	orphan := func(ev ecs.EventContainer, parentID ecs.EntityID, grandparentID ecs.EntityID) {
+		children := parents[parentID]
+		delete(parents, parentID)
+
+		// Every child may cause events, so they are published together.
+		var published ecs.EventBatch
+
+		sort.Slice(children, func(i, j int) bool { return children[i].EntityID < children[j].EntityID })
+		for _, c := range children {
+			child, ok := entities[c.EntityID]
+			if !ok {
+				continue
+			}
+
+			world := child.World
+			child.ParentID = 0
+
+			if child.OnParentRemoved == ReparentToGrandparent {
+				if err := setParent(&entities, &parents, c.EntityID, grandparentID); err != nil {
+					e.ReportError("TransformSystem", ev.Event, err)
 				}
+			}
+
+			// Keep the child where it was in the world, even if it is about to be destroyed.
+			child.setLocal(world.localTo(parentWorld(child)))
+			refresh(c.EntityID)
+
+			published = append(published, ParentRemovedEvent{c.EntityID, parentID, child.ParentID, child.OnParentRemoved})
+			if child.OnParentRemoved == DestroyWithParent {
+				published = append(published, ecs.EntityRemovedEvent{ID: c.EntityID})
+			}
+		}
+
+		if len(published) > 0 {
+			ev.Next <- published
+		}
+	}
+
+	// untrack stops tracking the transform of an entity, detaching it from its parent. Its children are orphaned,
+	// unless they are kept for a transform which replaces it.
+	untrack := func(ev ecs.EventContainer, id ecs.EntityID, keepChildren bool) {
+		entity, ok := entities[id]
+		if !ok {
+			return
+		}
+		grandparentID := entity.ParentID
+
+		// Change the parent to no-parent so that the entity is removed from any child lists.
+		if entity.ParentID != 0 {
+			if err := setParent(&entities, &parents, id, 0); err != nil {
+				e.ReportError("TransformSystem", ev.Event, err)
+			}
+		}
+
+		if !keepChildren {
+			orphan(ev, id, grandparentID)
+		}
 
-				// Turn absolute values into relative ones.
-				if event.Absolute {
-					event.OffsetX = event.OffsetX - transformedEntity.X
-					event.OffsetY = event.OffsetY - transformedEntity.Y
+		ecs.RemoveEntity(id, &entities)
+
+		if index != nil {
+			index.Remove(id)
+		}
+	}
+
+	go e.HandleEvents("TransformSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.EntityChange:
+			id, components := event.ChangedEntity()
+			transform, hasTransform := components[reflect.TypeOf(&Transform{})]
+
+			// Stop tracking the old transform if it has been removed or replaced. The children of a replaced transform
+			// are attached to the new one.
+			if entity, ok := entities[id]; ok && (!hasTransform || transform != entity.Transform) {
+				untrack(ev, id, hasTransform)
+			}
+
+			if _, ok := entities[id]; hasTransform && !ok {
+				track(event)
+			}
+
+		case ecs.EntityRemovedEvent:
+			untrack(ev, event.ID, false)
+
+		case ecs.UpdateEndEvent:
+			// Transforms may also have been changed directly, so world transforms are brought up to date from the
+			// top of each hierarchy down.
+			for _, id := range sortedIDs(entities) {
+				if entity := entities[id]; entity.ParentID == 0 || !hasEntity(entities, entity.ParentID) {
+					refreshChanged(id, false)
 				}
+			}
+
+			for _, entity := range entities {
+				entity.settle()
+			}
+
+			ev.Next <- TransformsRefreshedEvent{event.Delta}
+
+		case SetTransformParentEvent:
+			entity, ok := entities[event.EntityID]
+			if !ok {
+				e.ReportError("TransformSystem", event, errors.New("cannot set parent on nonexistent component"))
+				break
+			}
 
-				// Change the position
+			world := entity.World
+			if err := setParent(&entities, &parents, event.EntityID, event.ParentID); err != nil {
+				e.ReportError("TransformSystem", event, err)
+				break
+			}
+
+			// Keep the entity where it was in the world.
+			entity.setLocal(world.localTo(parentWorld(entity)))
+			refresh(event.EntityID)
+
+		case TransformEvent:
+			transformedEntity, ok := entities[event.EntityID]
+			if !ok {
+				e.ReportError("TransformSystem", event, errors.New("transform event on entity without a transform"))
+				break
+			}
+
+			if event.Absolute {
+				position := parentWorld(transformedEntity).Matrix().Unproject(pixel.V(event.OffsetX, event.OffsetY))
+				transformedEntity.X, transformedEntity.Y = position.X, position.Y
+			} else {
 				transformedEntity.X += event.OffsetX
 				transformedEntity.Y += event.OffsetY
+			}
 
-				// Propagate transformation to children.
-				children, ok := parents[event.EntityID]
-				if ok {
-					for _, child := range children {
-						ev.Next <- TransformEvent{child.EntityID, event.OffsetX, event.OffsetY, false}
-					}
-				}
+			// Children are moved along with the entity.
+			refresh(event.EntityID)
+
+		case RotateEvent:
+			rotatedEntity, ok := entities[event.EntityID]
+			if !ok {
+				e.ReportError("TransformSystem", event, errors.New("rotate event on entity without a transform"))
+				break
 			}
 
-			ev.Wg.Done()
+			rotatedEntity.Rotation += event.Offset
+			refresh(event.EntityID)
 		}
-	}()
+	})
+}
+
+// hasEntity returns true if the given entity is in the map.
+func hasEntity[T any](entities map[ecs.EntityID]T, id ecs.EntityID) bool {
+	_, ok := entities[id]
+	return ok
 }
 
 // Change the parent of the given entity to the given parent entity, updating the appropriate structures.
-func setParent(entities *map[uint64]eTransform, parents *map[uint64][]eTransformParent, childID uint64, newParentID uint64) {
+func setParent(entities *map[ecs.EntityID]eTransform, parents *map[ecs.EntityID][]eTransformParent, childID ecs.EntityID, newParentID ecs.EntityID) error {
 	comSet, ok := (*entities)[childID]
 	if !ok {
-		log.Fatal("Cannot set parent on nonexistent component")
+		return errors.New("cannot set parent on nonexistent component")
 	}
 
 	// Exit if we're trying to set the same exact parent ID
 	if comSet.Transform.ParentID == newParentID {
-		return
+		return nil
+	}
+
+	// Refuse to create a cycle, which would leave the entities without any position in the world.
+	for ancestorID := newParentID; ancestorID != 0; {
+		if ancestorID == childID {
+			return errors.New("cannot parent an entity to itself or one of its descendants")
+		}
+
+		ancestor, ok := (*entities)[ancestorID]
+		if !ok {
+			break
+		}
+		ancestorID = ancestor.ParentID
 	}
 
 	// Remove an entry from the old parent's list if it isn't no parent (0)
 	if comSet.Transform.ParentID != 0 {
 		oldParentList, ok := (*parents)[comSet.Transform.ParentID]
 		if !ok {
-			log.Fatal("Entity's old parent is invalid!?")
+			return errors.New("entity's old parent is invalid")
 		}
 
 		if len(oldParentList) == 0 {
@@ -140,7 +475,7 @@ func setParent(entities *map[uint64]eTransform, parents *map[uint64][]eTransform
 	comSet.Transform.ParentID = newParentID
 
 	if newParentID == 0 {
-		return
+		return nil
 	}
 
 	// Add an entry to the new parent's list if it isn't no parent (0)
@@ -150,4 +485,5 @@ func setParent(entities *map[uint64]eTransform, parents *map[uint64][]eTransform
 	}
 	(*parents)[newParentID] = append((*parents)[newParentID], eTransformParent{childID})
 
+	return nil
 }
@@ -0,0 +1,172 @@
+package systems
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"math"
+	"sync"
+	"testing"
+)
+
+// recordEvents keeps every event of the given types published by the ECS, in order.
+type recordEvents struct {
+	mutex  sync.Mutex
+	events []interface{}
+}
+
+func newRecordEvents(e *ecs.ECS, events ...interface{}) *recordEvents {
+	r := &recordEvents{}
+	go e.HandleEvents("EventRecorder", e.SubscribeTo(events...), func(ev ecs.EventContainer) {
+		r.mutex.Lock()
+		r.events = append(r.events, ev.Event)
+		r.mutex.Unlock()
+	})
+	return r
+}
+
+func (r *recordEvents) get() []interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]interface{}(nil), r.events...)
+}
+
+// removeParent builds a grandparent, parent and child with the given policy, then removes the parent.
+func removeParent(t *testing.T, policy ParentRemovalPolicy) (e *ecs.ECS, grandparent, child ecs.EntityID,
+	childTransform *Transform, events *recordEvents) {
+
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e = &world
+	TransformSystem(e, nil)
+	events = newRecordEvents(e, ParentRemovedEvent{})
+
+	grandparent = e.AddEntity(&Transform{X: 1000})
+	parent := e.AddEntity(&Transform{X: 100, Rotation: math.Pi / 2, ParentID: grandparent})
+	childTransform = &Transform{X: 10, ParentID: parent, OnParentRemoved: policy}
+	child = e.AddEntity(childTransform)
+	stepWithin(t, e, 1, 1.0/60)
+
+	e.RemoveEntity(parent)
+	stepWithin(t, e, 1, 1.0/60)
+
+	got := events.get()
+	if len(got) != 1 {
+		t.Fatalf("got events %v, want one ParentRemovedEvent", got)
+	}
+	if removed := got[0].(ParentRemovedEvent); removed.ID != child || removed.ParentID != parent ||
+		removed.Policy != policy {
+		t.Errorf("got %+v for child %v of parent %v", removed, child, parent)
+	}
+	return
+}
+
+func TestTransformDetachFromParent(t *testing.T) {
+	e, _, child, transform, _ := removeParent(t, DetachFromParent)
+	defer e.Close()
+
+	if !e.Alive(child) || transform.ParentID != 0 {
+		t.Fatalf("child alive: %v, parent: %v, want alive without a parent", e.Alive(child), transform.ParentID)
+	}
+	if transform.World.X != 1100 || math.Abs(transform.World.Y-10) > 1e-9 {
+		t.Errorf("child moved to %v, want (1100, 10)", transform.World.Position())
+	}
+}
+
+func TestTransformDestroyWithParent(t *testing.T) {
+	e, _, child, _, _ := removeParent(t, DestroyWithParent)
+	defer e.Close()
+
+	if e.Alive(child) {
+		t.Error("child survived its parent")
+	}
+}
+
+func TestTransformReparentToGrandparent(t *testing.T) {
+	e, grandparent, child, transform, _ := removeParent(t, ReparentToGrandparent)
+	defer e.Close()
+
+	if !e.Alive(child) || transform.ParentID != grandparent {
+		t.Fatalf("child alive: %v, parent: %v, want alive with parent %v", e.Alive(child), transform.ParentID,
+			grandparent)
+	}
+	if transform.World.X != 1100 || math.Abs(transform.World.Y-10) > 1e-9 {
+		t.Errorf("child moved to %v, want (1100, 10)", transform.World.Position())
+	}
+
+	// The child follows its new parent.
+	e.PublishNextFrame(TransformEvent{grandparent, 5, 0, false})
+	stepWithin(t, e, 1, 1.0/60)
+	if transform.World.X != 1105 {
+		t.Errorf("child is at %v after moving its new parent, want 1105", transform.World.X)
+	}
+}
+
+func TestTransformZeroPolicyKeepsChildren(t *testing.T) {
+	e, _, child, _, _ := removeParent(t, ParentRemovalPolicy(0))
+	defer e.Close()
+
+	if !e.Alive(child) {
+		t.Error("child without a policy was destroyed with its parent")
+	}
+}
+
+func TestTransformDestroyCascades(t *testing.T) {
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e := &world
+	defer e.Close()
+	TransformSystem(e, nil)
+
+	root := e.AddEntity(&Transform{})
+	var children []ecs.EntityID
+	for i := 0; i < 30; i++ {
+		children = append(children, e.AddEntity(&Transform{ParentID: root, OnParentRemoved: DestroyWithParent}))
+	}
+	grandchild := e.AddEntity(&Transform{ParentID: children[0], OnParentRemoved: DestroyWithParent})
+	survivor := e.AddEntity(&Transform{ParentID: children[1]})
+	stepWithin(t, e, 1, 1.0/60)
+
+	e.RemoveEntity(root)
+	stepWithin(t, e, 1, 1.0/60)
+
+	for _, id := range append(children, grandchild) {
+		if e.Alive(id) {
+			t.Errorf("%v survived", id)
+		}
+	}
+	if !e.Alive(survivor) {
+		t.Error("detached grandchild was destroyed")
+	}
+}
+
+func TestTransformHierarchy(t *testing.T) {
+	world := ecs.NewECS()
+	world.ErrorPolicy = ecs.ErrorPolicyCollect
+	e := &world
+	defer e.Close()
+	TransformSystem(e, nil)
+
+	parentTransform := &Transform{X: 100}
+	parent := e.AddEntity(parentTransform)
+	child := &Transform{X: 10, ParentID: parent}
+	childID := e.AddEntity(child)
+	stepWithin(t, e, 1, 1.0/60)
+
+	parentTransform.Rotation = math.Pi / 2
+	parentTransform.ScaleX, parentTransform.ScaleY = 2, 2
+	stepWithin(t, e, 1, 1.0/60)
+
+	if math.Abs(child.World.X-100) > 1e-9 || math.Abs(child.World.Y-20) > 1e-9 || child.World.ScaleX != 2 {
+		t.Errorf("child is at %+v, want (100, 20) scaled by 2", child.World)
+	}
+
+	e.PublishNextFrame(SetTransformParentEvent{parent, childID})
+	if err := e.Step(1, 1.0/60); err != nil {
+		t.Fatal(err)
+	}
+	if parentTransform.ParentID != 0 {
+		t.Error("an entity was parented to its own child")
+	}
+	if errs := e.Errors(); len(errs) != 1 || errs[0].System != "TransformSystem" {
+		t.Errorf("got errors %v, want the cycle reported by the transform system", errs)
+	}
+}
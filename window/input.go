@@ -0,0 +1,33 @@
+package window
+
+import (
+	"github.com/emctague/go-loopy/input"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Input adapts a pixelgl window to input.Input, so that systems can read the keyboard and mouse without depending on
+// pixelgl themselves.
+type Input struct {
+	Window *pixelgl.Window
+}
+
+// Pressed returns true if the button is held down.
+func (i Input) Pressed(button input.Button) bool {
+	return i.Window.Pressed(pixelgl.Button(button))
+}
+
+// JustPressed returns true if the button was pressed since the last window update.
+func (i Input) JustPressed(button input.Button) bool {
+	return i.Window.JustPressed(pixelgl.Button(button))
+}
+
+// MousePosition returns the position of the mouse within the window.
+func (i Input) MousePosition() pixel.Vec {
+	return i.Window.MousePosition()
+}
+
+// Bounds returns the bounds of the window.
+func (i Input) Bounds() pixel.Rect {
+	return i.Window.Bounds()
+}
@@ -0,0 +1,37 @@
+package window
+
+import (
+	"github.com/emctague/go-loopy/ecs"
+	"github.com/emctague/go-loopy/systems"
+	"github.com/faiface/pixel/pixelgl"
+	"image/color"
+)
+
+// RenderSystem is a system which draws to the screen.
+// Unlike other systems, the RenderSystem does not run itself in a goroutine - because PixelGL requires rendering to
+// occur in the main thread, RenderSystem takes it over and runs the passed whenReady function in a goroutine, where
+// the user of the RenderSystem may continue setup.
+func RenderSystem(e *ecs.ECS, win *pixelgl.Window, whenReady func()) {
+	scene := systems.NewScene()
+
+	events := e.SubscribeTo(append([]interface{}{ecs.RenderEvent{}}, systems.SceneEvents...)...)
+
+	go whenReady()
+
+	e.HandleEvents("RenderSystem", events, func(ev ecs.EventContainer) {
+		switch event := ev.Event.(type) {
+		case ecs.RenderEvent:
+
+			if win.Closed() {
+				e.Stop()
+			}
+
+			win.Clear(color.RGBA{R: 0, G: 0, B: 0, A: 255})
+			scene.Draw(win, event.Alpha)
+			win.Update()
+
+		default:
+			scene.Track(event)
+		}
+	})
+}